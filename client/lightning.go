@@ -2,17 +2,27 @@ package client
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"errors"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/lightningnetwork/lnd/lnrpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // LightningClient allows you to fetch Lightning node metrics from rpc.
 type LightningClient struct {
 	rpcclient lnrpc.LightningClient
+	info      *lnrpc.GetInfoResponse
+	timeout   time.Duration
+	mutex     sync.RWMutex
 }
 
+// DefaultTimeout is the default deadline of the rpc calls made by the client.
+const DefaultTimeout = 10 * time.Second
+
 type WalletStats struct {
 	TotalBallance      int64
 	ConfirmedBalance   int64
@@ -26,6 +36,14 @@ type NodeStats struct {
 	InactiveChannels uint32
 	BlockHeight      uint32
 	SyncedToChain    uint8
+
+	IdentityPubkey      string
+	Alias               string
+	Version             string
+	Chains              []string
+	Testnet             bool
+	Uris                []string
+	BestHeaderTimestamp int64
 }
 
 type PendingChannelsStats struct {
@@ -34,50 +52,267 @@ type PendingChannelsStats struct {
 	PendingClosingChannels      int
 	PendingForceClosingChannels int
 	WaitingCloseChannels        int
+	PendingOpen                 []PendingOpenChannelStats
+	PendingForceClosing         []ForceClosedChannelStats
+	WaitingClose                []WaitingCloseChannelStats
+}
+
+type PendingOpenChannelStats struct {
+	ChannelPoint       string
+	RemoteNodePub      string
+	ConfirmationHeight uint32
+	CommitFee          int64
+}
+
+type ForceClosedChannelStats struct {
+	ChannelPoint      string
+	RemoteNodePub     string
+	LimboBalance      int64
+	MaturityHeight    uint32
+	BlocksTilMaturity int32
+	RecoveredBalance  int64
+	PendingHtlcs      int
+}
+
+type WaitingCloseChannelStats struct {
+	ChannelPoint  string
+	RemoteNodePub string
+	LimboBalance  int64
 }
 
 type ChannelsBalanceStats struct {
 	TotalBalance int64
 }
 
-// NewLightningClient creates an LightningClient.
-func NewLightningClient(rpcclient lnrpc.LightningClient) (*LightningClient, error) {
+type ForwardingEvent struct {
+	ChanIDIn  uint64
+	ChanIDOut uint64
+	AmountIn  uint64
+	AmountOut uint64
+	Fee       uint64
+}
+
+type FeeReportStats struct {
+	DayFeeSum   uint64
+	WeekFeeSum  uint64
+	MonthFeeSum uint64
+	ChannelFees []ChannelFeeStats
+}
+
+type ChannelFeeStats struct {
+	ChannelPoint string
+	BaseFeeMsat  int64
+	FeePerMil    int64
+	FeeRate      float64
+}
+
+type PeerStats struct {
+	PubKey    string
+	Address   string
+	BytesSent uint64
+	BytesRecv uint64
+	SatSent   int64
+	SatRecv   int64
+	Inbound   bool
+	PingTime  int64
+}
+
+type ClosedChannelsStats struct {
+	Channels          int
+	Capacity          int64
+	SettledBalance    int64
+	TimeLockedBalance int64
+}
+
+type NetworkStats struct {
+	GraphDiameter        uint32
+	AvgOutDegree         float64
+	MaxOutDegree         uint32
+	NumNodes             uint32
+	NumChannels          uint32
+	TotalNetworkCapacity int64
+	AvgChannelSize       float64
+	MinChannelSize       int64
+	MaxChannelSize       int64
+}
+
+type InvoiceStats struct {
+	AddIndex     uint64
+	SettleIndex  uint64
+	Settled      bool
+	Value        int64
+	AmtPaidSat   int64
+	CreationDate int64
+	SettleDate   int64
+	Expiry       int64
+}
 
-	client := &LightningClient{
+type PaymentStats struct {
+	ValueSat   int64
+	Fee        int64
+	PathLength int
+}
+
+type ChannelStats struct {
+	ChanID           uint64
+	ChannelPoint     string
+	RemotePubkey     string
+	Active           bool
+	Private          bool
+	Capacity         int64
+	LocalBalance     int64
+	RemoteBalance    int64
+	UnsettledBalance int64
+	CommitFee        int64
+	NumUpdates       uint64
+	PendingHtlcs     int
+}
+
+// Node states reported by State.
+const (
+	// StateDisconnected is the state of a node that cannot be reached.
+	StateDisconnected = "disconnected"
+	// StateWalletLocked is the state of a node waiting for its wallet to be
+	// unlocked, which only serves the wallet unlocker rpc.
+	StateWalletLocked = "wallet_locked"
+	// StateReady is the state of a node serving the lightning rpc.
+	StateReady = "ready"
+)
+
+// ErrNotConnected is returned while the client has no rpc client to fetch the
+// metrics with.
+var ErrNotConnected = errors.New("not connected to the lightning node")
+
+// ErrNotReady is returned by LastBlockHeight and GetInfoStats when the last
+// state check did not find the node ready.
+var ErrNotReady = errors.New("the lightning node was not ready when last checked")
+
+// NewLightningClient creates an LightningClient. The rpc client can be nil
+// when the node cannot be connected to yet, and given later with
+// SetRPCClient.
+func NewLightningClient(rpcclient lnrpc.LightningClient) *LightningClient {
+	return &LightningClient{
 		rpcclient: rpcclient,
+		timeout:   DefaultTimeout,
+	}
+}
+
+// SetTimeout sets the deadline of the rpc calls, so a node that stops
+// answering cannot block the scrapes.
+func (client *LightningClient) SetTimeout(timeout time.Duration) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.timeout = timeout
+}
+
+// callContext returns the context of an rpc call, which is canceled once the
+// timeout of the client elapsed.
+func (client *LightningClient) callContext() (context.Context, context.CancelFunc) {
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+
+	return context.WithTimeout(context.Background(), client.timeout)
+}
+
+// SetRPCClient replaces the rpc client used to fetch the metrics, for instance
+// after reconnecting to the node.
+func (client *LightningClient) SetRPCClient(rpcclient lnrpc.LightningClient) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.rpcclient = rpcclient
+}
+
+func (client *LightningClient) rpc() (lnrpc.LightningClient, error) {
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+
+	if client.rpcclient == nil {
+		return nil, ErrNotConnected
 	}
+	return client.rpcclient, nil
+}
 
-	if _, err := client.GetStats(); err != nil {
-		return nil, fmt.Errorf("Failed to create LightningClient: %v", err)
+// State checks whether the node is ready to serve the lightning rpc, telling a
+// locked wallet apart from a node that cannot be reached. The GetInfo response
+// of the check is kept for LastBlockHeight and GetInfoStats.
+func (client *LightningClient) State() string {
+	info, err := client.GetStats()
+
+	client.mutex.Lock()
+	client.info = info
+	client.mutex.Unlock()
+
+	switch {
+	case err == nil:
+		return StateReady
+	case isWalletLocked(err):
+		return StateWalletLocked
+	default:
+		return StateDisconnected
 	}
+}
 
-	return client, nil
+// LastBlockHeight returns the block height of the node when its state was last
+// checked, saving a GetInfo call to the collectors that run after the check.
+func (client *LightningClient) LastBlockHeight() (uint32, error) {
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+
+	if client.info == nil {
+		return 0, ErrNotReady
+	}
+	return client.info.BlockHeight, nil
+}
+
+// isWalletLocked returns true when the error was returned by a node whose
+// wallet is locked. Such nodes only register the wallet unlocker service, so
+// the lightning rpc is unimplemented; later lnd versions report it explicitly.
+func isWalletLocked(err error) bool {
+	s, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	return s.Code() == codes.Unimplemented || strings.Contains(s.Message(), "wallet locked")
 }
 
 // GetStats fetches the node metrics.
 func (client *LightningClient) GetStats() (*lnrpc.GetInfoResponse, error) {
-	ctxb := context.Background()
+	ctxb, cancel := client.callContext()
+	defer cancel()
 
 	// Pending Channels
 	req := &lnrpc.GetInfoRequest{}
-	info, err := client.rpcclient.GetInfo(ctxb, req)
+	rpcclient, err := client.rpc()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := rpcclient.GetInfo(ctxb, req)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	return info, err
+	return info, nil
 }
 
 // GetWalletStats get wallet balances
 func (client *LightningClient) GetWalletStats() (*WalletStats, error) {
 	var stats WalletStats
 
-	ctxb := context.Background()
+	ctxb, cancel := client.callContext()
+	defer cancel()
 
 	req := &lnrpc.WalletBalanceRequest{}
-	wallet, err := client.rpcclient.WalletBalance(ctxb, req)
+	rpcclient, err := client.rpc()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+
+	wallet, err := rpcclient.WalletBalance(ctxb, req)
+	if err != nil {
+		return nil, err
 	}
 
 	stats.TotalBallance = wallet.TotalBalance
@@ -87,16 +322,17 @@ func (client *LightningClient) GetWalletStats() (*WalletStats, error) {
 	return &stats, nil
 }
 
-// GetInfoStats gets general node info
+// GetInfoStats gets general node info from the GetInfo response of the last
+// state check, so the info collector does not call GetInfo a second time.
 func (client *LightningClient) GetInfoStats() (*NodeStats, error) {
 	var stats NodeStats
 
-	ctxb := context.Background()
+	client.mutex.RLock()
+	info := client.info
+	client.mutex.RUnlock()
 
-	req := &lnrpc.GetInfoRequest{}
-	info, err := client.rpcclient.GetInfo(ctxb, req)
-	if err != nil {
-		log.Fatal(err)
+	if info == nil {
+		return nil, ErrNotReady
 	}
 	stats.Peers = info.NumPeers
 	stats.InactiveChannels = info.NumInactiveChannels
@@ -104,6 +340,13 @@ func (client *LightningClient) GetInfoStats() (*NodeStats, error) {
 	stats.PendingChannels = info.NumPendingChannels
 	stats.BlockHeight = info.BlockHeight
 	stats.SyncedToChain = boolToInt(info.SyncedToChain)
+	stats.IdentityPubkey = info.IdentityPubkey
+	stats.Alias = info.Alias
+	stats.Version = info.Version
+	stats.Chains = info.Chains
+	stats.Testnet = info.Testnet
+	stats.Uris = info.Uris
+	stats.BestHeaderTimestamp = info.BestHeaderTimestamp
 
 	return &stats, nil
 }
@@ -112,12 +355,18 @@ func (client *LightningClient) GetInfoStats() (*NodeStats, error) {
 func (client *LightningClient) GetPendingChannelsStats() (*PendingChannelsStats, error) {
 	var stats PendingChannelsStats
 
-	ctxb := context.Background()
+	ctxb, cancel := client.callContext()
+	defer cancel()
 
 	req := &lnrpc.PendingChannelsRequest{}
-	info, err := client.rpcclient.PendingChannels(ctxb, req)
+	rpcclient, err := client.rpc()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+
+	info, err := rpcclient.PendingChannels(ctxb, req)
+	if err != nil {
+		return nil, err
 	}
 
 	stats.TotalLimboBalance = info.TotalLimboBalance
@@ -126,6 +375,33 @@ func (client *LightningClient) GetPendingChannelsStats() (*PendingChannelsStats,
 	stats.PendingForceClosingChannels = len(info.PendingForceClosingChannels)
 	stats.WaitingCloseChannels = len(info.WaitingCloseChannels)
 
+	for _, channel := range info.PendingOpenChannels {
+		stats.PendingOpen = append(stats.PendingOpen, PendingOpenChannelStats{
+			ChannelPoint:       channel.GetChannel().GetChannelPoint(),
+			RemoteNodePub:      channel.GetChannel().GetRemoteNodePub(),
+			ConfirmationHeight: channel.ConfirmationHeight,
+			CommitFee:          channel.CommitFee,
+		})
+	}
+	for _, channel := range info.PendingForceClosingChannels {
+		stats.PendingForceClosing = append(stats.PendingForceClosing, ForceClosedChannelStats{
+			ChannelPoint:      channel.GetChannel().GetChannelPoint(),
+			RemoteNodePub:     channel.GetChannel().GetRemoteNodePub(),
+			LimboBalance:      channel.LimboBalance,
+			MaturityHeight:    channel.MaturityHeight,
+			BlocksTilMaturity: channel.BlocksTilMaturity,
+			RecoveredBalance:  channel.RecoveredBalance,
+			PendingHtlcs:      len(channel.PendingHtlcs),
+		})
+	}
+	for _, channel := range info.WaitingCloseChannels {
+		stats.WaitingClose = append(stats.WaitingClose, WaitingCloseChannelStats{
+			ChannelPoint:  channel.GetChannel().GetChannelPoint(),
+			RemoteNodePub: channel.GetChannel().GetRemoteNodePub(),
+			LimboBalance:  channel.LimboBalance,
+		})
+	}
+
 	return &stats, nil
 }
 
@@ -133,12 +409,18 @@ func (client *LightningClient) GetPendingChannelsStats() (*PendingChannelsStats,
 func (client *LightningClient) GetChannelsBalanceStats() (*ChannelsBalanceStats, error) {
 	var stats ChannelsBalanceStats
 
-	ctxb := context.Background()
+	ctxb, cancel := client.callContext()
+	defer cancel()
 
 	req := &lnrpc.ChannelBalanceRequest{}
-	info, err := client.rpcclient.ChannelBalance(ctxb, req)
+	rpcclient, err := client.rpc()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := rpcclient.ChannelBalance(ctxb, req)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	stats.TotalBalance = info.Balance
@@ -146,6 +428,278 @@ func (client *LightningClient) GetChannelsBalanceStats() (*ChannelsBalanceStats,
 	return &stats, nil
 }
 
+// GetChannelsStats gets the balances of every open channel
+func (client *LightningClient) GetChannelsStats() ([]ChannelStats, error) {
+	ctxb, cancel := client.callContext()
+	defer cancel()
+
+	req := &lnrpc.ListChannelsRequest{}
+	rpcclient, err := client.rpc()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := rpcclient.ListChannels(ctxb, req)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]ChannelStats, 0, len(info.Channels))
+	for _, channel := range info.Channels {
+		stats = append(stats, ChannelStats{
+			ChanID:           channel.ChanId,
+			ChannelPoint:     channel.ChannelPoint,
+			RemotePubkey:     channel.RemotePubkey,
+			Active:           channel.Active,
+			Private:          channel.Private,
+			Capacity:         channel.Capacity,
+			LocalBalance:     channel.LocalBalance,
+			RemoteBalance:    channel.RemoteBalance,
+			UnsettledBalance: channel.UnsettledBalance,
+			CommitFee:        channel.CommitFee,
+			NumUpdates:       channel.NumUpdates,
+			PendingHtlcs:     len(channel.PendingHtlcs),
+		})
+	}
+
+	return stats, nil
+}
+
+// GetForwardingHistory gets up to maxEvents forwarding events starting at the
+// given index offset, along with the offset to resume from
+func (client *LightningClient) GetForwardingHistory(indexOffset uint32, maxEvents uint32) ([]ForwardingEvent, uint32, error) {
+	ctxb, cancel := client.callContext()
+	defer cancel()
+
+	req := &lnrpc.ForwardingHistoryRequest{
+		EndTime:      uint64(time.Now().Unix()),
+		IndexOffset:  indexOffset,
+		NumMaxEvents: maxEvents,
+	}
+	rpcclient, err := client.rpc()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := rpcclient.ForwardingHistory(ctxb, req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events := make([]ForwardingEvent, 0, len(info.ForwardingEvents))
+	for _, event := range info.ForwardingEvents {
+		events = append(events, ForwardingEvent{
+			ChanIDIn:  event.ChanIdIn,
+			ChanIDOut: event.ChanIdOut,
+			AmountIn:  event.AmtIn,
+			AmountOut: event.AmtOut,
+			Fee:       event.Fee,
+		})
+	}
+
+	return events, info.LastOffsetIndex, nil
+}
+
+// GetFeeReportStats gets the fee policies of the channels and the fees earned
+func (client *LightningClient) GetFeeReportStats() (*FeeReportStats, error) {
+	var stats FeeReportStats
+
+	ctxb, cancel := client.callContext()
+	defer cancel()
+
+	req := &lnrpc.FeeReportRequest{}
+	rpcclient, err := client.rpc()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := rpcclient.FeeReport(ctxb, req)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.DayFeeSum = info.DayFeeSum
+	stats.WeekFeeSum = info.WeekFeeSum
+	stats.MonthFeeSum = info.MonthFeeSum
+	for _, channel := range info.ChannelFees {
+		stats.ChannelFees = append(stats.ChannelFees, ChannelFeeStats{
+			ChannelPoint: channel.ChanPoint,
+			BaseFeeMsat:  channel.BaseFeeMsat,
+			FeePerMil:    channel.FeePerMil,
+			FeeRate:      channel.FeeRate,
+		})
+	}
+
+	return &stats, nil
+}
+
+// GetPeersStats gets the traffic and latency of every connected peer
+func (client *LightningClient) GetPeersStats() ([]PeerStats, error) {
+	ctxb, cancel := client.callContext()
+	defer cancel()
+
+	req := &lnrpc.ListPeersRequest{}
+	rpcclient, err := client.rpc()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := rpcclient.ListPeers(ctxb, req)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]PeerStats, 0, len(info.Peers))
+	for _, peer := range info.Peers {
+		stats = append(stats, PeerStats{
+			PubKey:    peer.PubKey,
+			Address:   peer.Address,
+			BytesSent: peer.BytesSent,
+			BytesRecv: peer.BytesRecv,
+			SatSent:   peer.SatSent,
+			SatRecv:   peer.SatRecv,
+			Inbound:   peer.Inbound,
+			PingTime:  peer.PingTime,
+		})
+	}
+
+	return stats, nil
+}
+
+// GetClosedChannelsStats gets the closed channels totals by closure type
+func (client *LightningClient) GetClosedChannelsStats() (map[string]*ClosedChannelsStats, error) {
+	ctxb, cancel := client.callContext()
+	defer cancel()
+
+	req := &lnrpc.ClosedChannelsRequest{}
+	rpcclient, err := client.rpc()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := rpcclient.ClosedChannels(ctxb, req)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := map[string]*ClosedChannelsStats{}
+	for _, closeType := range lnrpc.ChannelCloseSummary_ClosureType_name {
+		stats[strings.ToLower(closeType)] = &ClosedChannelsStats{}
+	}
+	for _, channel := range info.Channels {
+		closeType := strings.ToLower(channel.CloseType.String())
+		if _, ok := stats[closeType]; !ok {
+			stats[closeType] = &ClosedChannelsStats{}
+		}
+		stats[closeType].Channels++
+		stats[closeType].Capacity += channel.Capacity
+		stats[closeType].SettledBalance += channel.SettledBalance
+		stats[closeType].TimeLockedBalance += channel.TimeLockedBalance
+	}
+
+	return stats, nil
+}
+
+// GetNetworkStats gets the statistics of the network graph
+func (client *LightningClient) GetNetworkStats() (*NetworkStats, error) {
+	var stats NetworkStats
+
+	ctxb, cancel := client.callContext()
+	defer cancel()
+
+	req := &lnrpc.NetworkInfoRequest{}
+	rpcclient, err := client.rpc()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := rpcclient.GetNetworkInfo(ctxb, req)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.GraphDiameter = info.GraphDiameter
+	stats.AvgOutDegree = info.AvgOutDegree
+	stats.MaxOutDegree = info.MaxOutDegree
+	stats.NumNodes = info.NumNodes
+	stats.NumChannels = info.NumChannels
+	stats.TotalNetworkCapacity = info.TotalNetworkCapacity
+	stats.AvgChannelSize = info.AvgChannelSize
+	stats.MinChannelSize = info.MinChannelSize
+	stats.MaxChannelSize = info.MaxChannelSize
+
+	return &stats, nil
+}
+
+// GetInvoices gets up to maxInvoices invoices added after the given index
+// offset, along with the offset to resume from
+func (client *LightningClient) GetInvoices(indexOffset uint64, maxInvoices uint64) ([]InvoiceStats, uint64, error) {
+	ctxb, cancel := client.callContext()
+	defer cancel()
+
+	req := &lnrpc.ListInvoiceRequest{
+		IndexOffset:    indexOffset,
+		NumMaxInvoices: maxInvoices,
+	}
+	rpcclient, err := client.rpc()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := rpcclient.ListInvoices(ctxb, req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	invoices := make([]InvoiceStats, 0, len(info.Invoices))
+	for _, invoice := range info.Invoices {
+		invoices = append(invoices, newInvoiceStats(invoice))
+	}
+
+	return invoices, info.LastIndexOffset, nil
+}
+
+func newInvoiceStats(invoice *lnrpc.Invoice) InvoiceStats {
+	return InvoiceStats{
+		AddIndex:     invoice.AddIndex,
+		SettleIndex:  invoice.SettleIndex,
+		Settled:      invoice.Settled,
+		Value:        invoice.Value,
+		AmtPaidSat:   invoice.AmtPaidSat,
+		CreationDate: invoice.CreationDate,
+		SettleDate:   invoice.SettleDate,
+		Expiry:       invoice.Expiry,
+	}
+}
+
+// GetPaymentsStats gets the value, fee and path length of every payment made
+func (client *LightningClient) GetPaymentsStats() ([]PaymentStats, error) {
+	ctxb, cancel := client.callContext()
+	defer cancel()
+
+	req := &lnrpc.ListPaymentsRequest{}
+	rpcclient, err := client.rpc()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := rpcclient.ListPayments(ctxb, req)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]PaymentStats, 0, len(info.Payments))
+	for _, payment := range info.Payments {
+		stats = append(stats, PaymentStats{
+			ValueSat:   payment.ValueSat,
+			Fee:        payment.Fee,
+			PathLength: len(payment.Path),
+		})
+	}
+
+	return stats, nil
+}
+
 func boolToInt(arg bool) uint8 {
 	if arg {
 		return 1
@@ -0,0 +1,203 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/platanus/lightning-prometheus-exporter/internal/lndtest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startNode starts a fake lnd and returns a client connected to it.
+func startNode(t *testing.T) (*lndtest.Node, *LightningClient) {
+	node, err := lndtest.StartNode(lndtest.Options{})
+	if err != nil {
+		t.Fatalf("starting fake lnd failed: %v", err)
+	}
+	conn, err := node.Dial()
+	if err != nil {
+		node.Close()
+		t.Fatalf("connecting to fake lnd failed: %v", err)
+	}
+
+	return node, NewLightningClient(lnrpc.NewLightningClient(conn))
+}
+
+func TestGetInfoStats(t *testing.T) {
+	node, client := startNode(t)
+	defer node.Close()
+
+	node.Script("GetInfo", lndtest.Script{Response: &lnrpc.GetInfoResponse{
+		IdentityPubkey:      "02aaaa",
+		Alias:               "alice",
+		NumPeers:            3,
+		NumActiveChannels:   2,
+		BlockHeight:         600000,
+		SyncedToChain:       true,
+		Chains:              []string{"bitcoin"},
+		BestHeaderTimestamp: 1570000000,
+	}})
+
+	if _, err := client.GetInfoStats(); err != ErrNotReady {
+		t.Errorf("expected ErrNotReady before the node state is checked, got %v", err)
+	}
+
+	client.State()
+	stats, err := client.GetInfoStats()
+	if err != nil {
+		t.Fatalf("GetInfoStats failed: %v", err)
+	}
+	if stats.IdentityPubkey != "02aaaa" || stats.Alias != "alice" || stats.Peers != 3 || stats.ActiveChannels != 2 ||
+		stats.BlockHeight != 600000 || stats.SyncedToChain != 1 || stats.BestHeaderTimestamp != 1570000000 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestGetForwardingHistory(t *testing.T) {
+	node, client := startNode(t)
+	defer node.Close()
+
+	node.Script("ForwardingHistory", lndtest.Script{Response: &lnrpc.ForwardingHistoryResponse{
+		ForwardingEvents: []*lnrpc.ForwardingEvent{
+			{ChanIdIn: 1, ChanIdOut: 2, AmtIn: 1010, AmtOut: 1000, Fee: 10},
+		},
+		LastOffsetIndex: 7,
+	}})
+
+	events, offset, err := client.GetForwardingHistory(6, 100)
+	if err != nil {
+		t.Fatalf("GetForwardingHistory failed: %v", err)
+	}
+	if offset != 7 {
+		t.Errorf("expected offset 7, got %d", offset)
+	}
+	expected := ForwardingEvent{ChanIDIn: 1, ChanIDOut: 2, AmountIn: 1010, AmountOut: 1000, Fee: 10}
+	if len(events) != 1 || events[0] != expected {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
+
+func TestScriptedErrorAndLatency(t *testing.T) {
+	node, client := startNode(t)
+	defer node.Close()
+
+	node.Script("WalletBalance", lndtest.Script{
+		Err:     status.Error(codes.Internal, "wallet failure"),
+		Latency: 50 * time.Millisecond,
+	})
+
+	start := time.Now()
+	_, err := client.GetWalletStats()
+	if status.Code(err) != codes.Internal {
+		t.Errorf("expected the scripted error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected the call to take the scripted latency, took %v", elapsed)
+	}
+	if calls := node.Calls("WalletBalance"); calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestUnimplementedMethod(t *testing.T) {
+	node, _ := startNode(t)
+	defer node.Close()
+
+	conn, err := node.Dial()
+	if err != nil {
+		t.Fatalf("connecting to fake lnd failed: %v", err)
+	}
+	defer conn.Close()
+
+	// A method the fake lnd does not serve fails without taking the server
+	// down, so the calls made after it are still answered.
+	rpcclient := lnrpc.NewLightningClient(conn)
+	if _, err := rpcclient.SendCoins(context.Background(), &lnrpc.SendCoinsRequest{}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("expected an Unimplemented status, got %v", err)
+	}
+	if _, err := rpcclient.GetInfo(context.Background(), &lnrpc.GetInfoRequest{}); err != nil {
+		t.Errorf("GetInfo failed after the unimplemented call: %v", err)
+	}
+}
+
+func TestState(t *testing.T) {
+	node, client := startNode(t)
+	defer node.Close()
+
+	if state := client.State(); state != StateReady {
+		t.Errorf("expected %s, got %s", StateReady, state)
+	}
+
+	node.Script("GetInfo", lndtest.Script{Err: status.Error(codes.Unimplemented, "unknown service lnrpc.Lightning")})
+	if state := client.State(); state != StateWalletLocked {
+		t.Errorf("expected %s with the lightning service unimplemented, got %s", StateWalletLocked, state)
+	}
+
+	node.Script("GetInfo", lndtest.Script{Err: status.Error(codes.Unknown, "wallet locked, unlock it to enable full RPC access")})
+	if state := client.State(); state != StateWalletLocked {
+		t.Errorf("expected %s with the wallet locked error, got %s", StateWalletLocked, state)
+	}
+
+	if state := NewLightningClient(nil).State(); state != StateDisconnected {
+		t.Errorf("expected %s without rpc client, got %s", StateDisconnected, state)
+	}
+
+	node.Close()
+	if state := client.State(); state != StateDisconnected {
+		t.Errorf("expected %s once lnd stopped, got %s", StateDisconnected, state)
+	}
+}
+
+func TestSubscribeTransactions(t *testing.T) {
+	node, client := startNode(t)
+	defer node.Close()
+
+	node.Script("SubscribeTransactions", lndtest.Script{Events: []proto.Message{
+		&lnrpc.Transaction{Amount: 50000},
+		&lnrpc.Transaction{Amount: -20000, TotalFees: 250},
+	}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opened := false
+	transactions := []TransactionStats{}
+	err := client.SubscribeTransactions(ctx, func() { opened = true }, func(transaction TransactionStats) {
+		transactions = append(transactions, transaction)
+		if len(transactions) == 2 {
+			cancel()
+		}
+	})
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("expected the stream to be canceled, got %v", err)
+	}
+	if !opened {
+		t.Error("expected opened to be called")
+	}
+
+	expected := []TransactionStats{{Amount: 50000}, {Amount: -20000, TotalFees: 250}}
+	if len(transactions) != 2 || transactions[0] != expected[0] || transactions[1] != expected[1] {
+		t.Errorf("unexpected transactions: %+v", transactions)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	node, client := startNode(t)
+	defer node.Close()
+
+	node.Script("WalletBalance", lndtest.Script{Latency: time.Second})
+	client.SetTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	_, err := client.GetWalletStats()
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expected the call to exceed its deadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the call to be canceled after the timeout, took %v", elapsed)
+	}
+}
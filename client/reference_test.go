@@ -0,0 +1,76 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// startBitcoind starts a bitcoind stand-in answering getblockcount with the
+// given status and body, and returns the url of its endpoint with the rpc
+// credentials.
+func startBitcoind(t *testing.T, status int, body string) (*httptest.Server, string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "user" || password != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var request bitcoindRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Method != "getblockcount" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+
+	return server, strings.Replace(server.URL, "http://", "http://user:password@", 1)
+}
+
+func TestBitcoindGetBlockHeight(t *testing.T) {
+	server, url := startBitcoind(t, http.StatusOK, `{"result":600002,"error":null,"id":"lightning-prometheus-exporter"}`)
+	defer server.Close()
+
+	height, err := NewBitcoindClient(url).GetBlockHeight()
+	if err != nil {
+		t.Fatalf("GetBlockHeight failed: %v", err)
+	}
+	if height != 600002 {
+		t.Errorf("expected height 600002, got %d", height)
+	}
+}
+
+func TestBitcoindGetBlockHeightError(t *testing.T) {
+	server, url := startBitcoind(t, http.StatusInternalServerError, `{"result":null,"error":{"code":-28,"message":"Loading block index..."},"id":"lightning-prometheus-exporter"}`)
+	defer server.Close()
+
+	_, err := NewBitcoindClient(url).GetBlockHeight()
+	if err == nil || !strings.Contains(err.Error(), "bitcoind error -28: Loading block index...") {
+		t.Errorf("expected the bitcoind error, got %v", err)
+	}
+}
+
+func TestEsploraGetBlockHeight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/blocks/tip/height" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "600002\n")
+	}))
+	defer server.Close()
+
+	height, err := NewEsploraClient(server.URL + "/").GetBlockHeight()
+	if err != nil {
+		t.Fatalf("GetBlockHeight failed: %v", err)
+	}
+	if height != 600002 {
+		t.Errorf("expected height 600002, got %d", height)
+	}
+}
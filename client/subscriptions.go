@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+type TransactionStats struct {
+	NumConfirmations int32
+	Amount           int64
+	TotalFees        int64
+}
+
+type GraphUpdateStats struct {
+	NodeUpdates    int
+	ChannelUpdates int
+	ClosedChannels int
+}
+
+// SubscribeInvoices streams to handler the invoices added after addIndex and
+// settled after settleIndex, calling opened once the stream is open and
+// blocking until the stream fails
+func (client *LightningClient) SubscribeInvoices(ctx context.Context, addIndex uint64, settleIndex uint64, opened func(), handler func(InvoiceStats)) error {
+	req := &lnrpc.InvoiceSubscription{
+		AddIndex:    addIndex,
+		SettleIndex: settleIndex,
+	}
+	rpcclient, err := client.rpc()
+	if err != nil {
+		return err
+	}
+
+	stream, err := rpcclient.SubscribeInvoices(ctx, req)
+	if err != nil {
+		return err
+	}
+	opened()
+
+	for {
+		invoice, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		handler(newInvoiceStats(invoice))
+	}
+}
+
+// SubscribeTransactions streams to handler the wallet transactions, calling
+// opened once the stream is open and blocking until the stream fails. lnd
+// sends a transaction when it is first seen unconfirmed and again when it
+// confirms
+func (client *LightningClient) SubscribeTransactions(ctx context.Context, opened func(), handler func(TransactionStats)) error {
+	req := &lnrpc.GetTransactionsRequest{}
+	rpcclient, err := client.rpc()
+	if err != nil {
+		return err
+	}
+
+	stream, err := rpcclient.SubscribeTransactions(ctx, req)
+	if err != nil {
+		return err
+	}
+	opened()
+
+	for {
+		transaction, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		handler(TransactionStats{
+			NumConfirmations: transaction.NumConfirmations,
+			Amount:           transaction.Amount,
+			TotalFees:        transaction.TotalFees,
+		})
+	}
+}
+
+// SubscribeChannelGraph streams to handler the network graph updates, calling
+// opened once the stream is open and blocking until the stream fails
+func (client *LightningClient) SubscribeChannelGraph(ctx context.Context, opened func(), handler func(GraphUpdateStats)) error {
+	req := &lnrpc.GraphTopologySubscription{}
+	rpcclient, err := client.rpc()
+	if err != nil {
+		return err
+	}
+
+	stream, err := rpcclient.SubscribeChannelGraph(ctx, req)
+	if err != nil {
+		return err
+	}
+	opened()
+
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		handler(GraphUpdateStats{
+			NodeUpdates:    len(update.NodeUpdates),
+			ChannelUpdates: len(update.ChannelUpdates),
+			ClosedChannels: len(update.ClosedChans),
+		})
+	}
+}
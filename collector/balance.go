@@ -0,0 +1,40 @@
+package collector
+
+import (
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("balance", "channel_balance", true, newBalanceCollector)
+}
+
+type balanceCollector struct {
+	lightningClient *client.LightningClient
+	metrics         map[string]*prometheus.Desc
+}
+
+func newBalanceCollector(lightningClient *client.LightningClient, namespace string) Collector {
+	return &balanceCollector{
+		lightningClient: lightningClient,
+		metrics: map[string]*prometheus.Desc{
+			"channels_balance_satoshis": newGlobalMetric(namespace, "channels_balance_satoshis", "Sum of all channel funds available", []string{}),
+		},
+	}
+}
+
+func (c *balanceCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeMetrics(ch, c.metrics)
+}
+
+func (c *balanceCollector) Update(ch chan<- prometheus.Metric) error {
+	channelBalanceStats, err := c.lightningClient.GetChannelsBalanceStats()
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.metrics["channels_balance_satoshis"],
+		prometheus.GaugeValue, float64(channelBalanceStats.TotalBalance))
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"strconv"
+
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var channelLabels = []string{"chan_id", "channel_point", "remote_pubkey", "active", "private"}
+
+func init() {
+	registerCollector("channels", "list_channels", true, newChannelsCollector)
+}
+
+type channelsCollector struct {
+	lightningClient *client.LightningClient
+	metrics         map[string]*prometheus.Desc
+}
+
+func newChannelsCollector(lightningClient *client.LightningClient, namespace string) Collector {
+	return &channelsCollector{
+		lightningClient: lightningClient,
+		metrics: map[string]*prometheus.Desc{
+			"channel_capacity_satoshis":          newGlobalMetric(namespace, "channel_capacity_satoshis", "The total amount of funds held in the channel", channelLabels),
+			"channel_local_balance_satoshis":     newGlobalMetric(namespace, "channel_local_balance_satoshis", "The channel balance available to this node", channelLabels),
+			"channel_remote_balance_satoshis":    newGlobalMetric(namespace, "channel_remote_balance_satoshis", "The channel balance available to the remote node", channelLabels),
+			"channel_unsettled_balance_satoshis": newGlobalMetric(namespace, "channel_unsettled_balance_satoshis", "The channel balance encumbered in pending HTLCs", channelLabels),
+			"channel_commit_fee_satoshis":        newGlobalMetric(namespace, "channel_commit_fee_satoshis", "The fee the channel initiator pays for the commitment transaction", channelLabels),
+			"channel_updates":                    newGlobalMetric(namespace, "channel_updates", "The number of updates to the channel commitment transaction", channelLabels),
+			"channel_pending_htlcs":              newGlobalMetric(namespace, "channel_pending_htlcs", "The number of HTLCs pending in the channel", channelLabels),
+		},
+	}
+}
+
+func (c *channelsCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeMetrics(ch, c.metrics)
+}
+
+func (c *channelsCollector) Update(ch chan<- prometheus.Metric) error {
+	channelsStats, err := c.lightningClient.GetChannelsStats()
+	if err != nil {
+		return err
+	}
+
+	for _, channel := range channelsStats {
+		labels := []string{
+			strconv.FormatUint(channel.ChanID, 10),
+			channel.ChannelPoint,
+			channel.RemotePubkey,
+			strconv.FormatBool(channel.Active),
+			strconv.FormatBool(channel.Private),
+		}
+		ch <- prometheus.MustNewConstMetric(c.metrics["channel_capacity_satoshis"],
+			prometheus.GaugeValue, float64(channel.Capacity), labels...)
+		ch <- prometheus.MustNewConstMetric(c.metrics["channel_local_balance_satoshis"],
+			prometheus.GaugeValue, float64(channel.LocalBalance), labels...)
+		ch <- prometheus.MustNewConstMetric(c.metrics["channel_remote_balance_satoshis"],
+			prometheus.GaugeValue, float64(channel.RemoteBalance), labels...)
+		ch <- prometheus.MustNewConstMetric(c.metrics["channel_unsettled_balance_satoshis"],
+			prometheus.GaugeValue, float64(channel.UnsettledBalance), labels...)
+		ch <- prometheus.MustNewConstMetric(c.metrics["channel_commit_fee_satoshis"],
+			prometheus.GaugeValue, float64(channel.CommitFee), labels...)
+		ch <- prometheus.MustNewConstMetric(c.metrics["channel_updates"],
+			prometheus.GaugeValue, float64(channel.NumUpdates), labels...)
+		ch <- prometheus.MustNewConstMetric(c.metrics["channel_pending_htlcs"],
+			prometheus.GaugeValue, float64(channel.PendingHtlcs), labels...)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("closed", "closed_channels", true, newClosedCollector)
+}
+
+type closedCollector struct {
+	lightningClient *client.LightningClient
+	metrics         map[string]*prometheus.Desc
+}
+
+func newClosedCollector(lightningClient *client.LightningClient, namespace string) Collector {
+	return &closedCollector{
+		lightningClient: lightningClient,
+		metrics: map[string]*prometheus.Desc{
+			"closed_channels":                              newGlobalMetric(namespace, "closed_channels", "Number of closed channels", []string{"close_type"}),
+			"closed_channels_capacity_satoshis":            newGlobalMetric(namespace, "closed_channels_capacity_satoshis", "Total capacity of the closed channels", []string{"close_type"}),
+			"closed_channels_settled_balance_satoshis":     newGlobalMetric(namespace, "closed_channels_settled_balance_satoshis", "Total balance settled to this node on channel close", []string{"close_type"}),
+			"closed_channels_time_locked_balance_satoshis": newGlobalMetric(namespace, "closed_channels_time_locked_balance_satoshis", "Total balance time-locked on channel close", []string{"close_type"}),
+		},
+	}
+}
+
+func (c *closedCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeMetrics(ch, c.metrics)
+}
+
+func (c *closedCollector) Update(ch chan<- prometheus.Metric) error {
+	closedChannelsStats, err := c.lightningClient.GetClosedChannelsStats()
+	if err != nil {
+		return err
+	}
+
+	for closeType, stats := range closedChannelsStats {
+		ch <- prometheus.MustNewConstMetric(c.metrics["closed_channels"],
+			prometheus.GaugeValue, float64(stats.Channels), closeType)
+		ch <- prometheus.MustNewConstMetric(c.metrics["closed_channels_capacity_satoshis"],
+			prometheus.GaugeValue, float64(stats.Capacity), closeType)
+		ch <- prometheus.MustNewConstMetric(c.metrics["closed_channels_settled_balance_satoshis"],
+			prometheus.GaugeValue, float64(stats.SettledBalance), closeType)
+		ch <- prometheus.MustNewConstMetric(c.metrics["closed_channels_time_locked_balance_satoshis"],
+			prometheus.GaugeValue, float64(stats.TimeLockedBalance), closeType)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is the interface a sub-collector has to implement.
+type Collector interface {
+	// Describe sends the descriptors of the sub-collector metrics to the
+	// provided channel.
+	Describe(ch chan<- *prometheus.Desc)
+	// Update fetches the stats of the sub-collector and sends the resulting
+	// metrics to the provided channel.
+	Update(ch chan<- prometheus.Metric) error
+}
+
+// backgroundCollector is implemented by the sub-collectors that keep working
+// between scrapes. They are started by LightningCollector.Start.
+type backgroundCollector interface {
+	Collector
+	// Start runs the background work of the sub-collector until the context
+	// is done.
+	Start(ctx context.Context)
+}
+
+type factoryFunc func(lightningClient *client.LightningClient, namespace string) Collector
+
+type registration struct {
+	rpc      string
+	enabled  *bool
+	factory  factoryFunc
+	validate func() error
+}
+
+var registrations = map[string]*registration{}
+
+// registerCollector makes a sub-collector available under the given name and
+// adds its --collector.<name> and --no-collector.<name> flags. The rpc is the
+// lnd call made by the sub-collector, used to label its scrape metrics.
+func registerCollector(name string, rpc string, isDefaultEnabled bool, factory factoryFunc) {
+	enabled := isDefaultEnabled
+
+	flag.Var(&collectorFlag{enabled: &enabled, value: true}, "collector."+name,
+		fmt.Sprintf("Enable the %s collector.", name))
+	flag.Var(&collectorFlag{enabled: &enabled, value: false}, "no-collector."+name,
+		fmt.Sprintf("Disable the %s collector.", name))
+
+	registrations[name] = &registration{
+		rpc:     rpc,
+		enabled: &enabled,
+		factory: factory,
+	}
+}
+
+// registerValidator sets the function checking the settings of the
+// sub-collector registered under the given name.
+func registerValidator(name string, validate func() error) {
+	registrations[name].validate = validate
+}
+
+// ValidateCollectors checks the settings of the enabled sub-collectors, so
+// invalid ones are reported at startup rather than on every scrape.
+func ValidateCollectors() error {
+	for _, name := range EnabledCollectors() {
+		if validate := registrations[name].validate; validate != nil {
+			if err := validate(); err != nil {
+				return fmt.Errorf("%s collector: %v", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// EnabledCollectors returns the sorted names of the enabled sub-collectors.
+func EnabledCollectors() []string {
+	names := []string{}
+	for name, r := range registrations {
+		if *r.enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// collectorFlag is a boolean flag that sets whether a sub-collector is
+// enabled. The value is what the collector state is set to when the flag is
+// given as true, so it also serves the --no-collector.<name> flags.
+type collectorFlag struct {
+	enabled *bool
+	value   bool
+}
+
+func (f *collectorFlag) IsBoolFlag() bool {
+	return true
+}
+
+func (f *collectorFlag) String() string {
+	if f.enabled == nil {
+		return ""
+	}
+	return strconv.FormatBool(*f.enabled == f.value)
+}
+
+func (f *collectorFlag) Set(value string) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*f.enabled = b == f.value
+	return nil
+}
@@ -0,0 +1,234 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	streamMinBackoff = time.Second
+	streamMaxBackoff = time.Minute
+)
+
+// eventStreams are the names of the streams kept open by the events
+// collector.
+var eventStreams = []string{"invoices", "transactions", "graph"}
+
+func init() {
+	registerCollector("events", "subscribe", false, newEventsCollector)
+}
+
+// eventsCollector keeps streams to SubscribeInvoices, SubscribeTransactions
+// and SubscribeChannelGraph open in the background and counts their events as
+// they arrive, so events between scrapes are never missed. The streams are
+// opened by Start and failed streams are reopened with an exponential
+// backoff. The invoices stream resumes from the last add_index and
+// settle_index seen, so reconnecting does not lose them. The scrape fails
+// while any stream is not open.
+type eventsCollector struct {
+	lightningClient *client.LightningClient
+	invoices        *prometheus.CounterVec
+	invoicesValue   *prometheus.CounterVec
+	transactions    *prometheus.CounterVec
+	transactionFees prometheus.Counter
+	graphUpdates    *prometheus.CounterVec
+	streamUp        *prometheus.GaugeVec
+	streamRestarts  *prometheus.CounterVec
+	addIndex        uint64
+	settleIndex     uint64
+	open            map[string]bool
+	mutex           sync.Mutex
+}
+
+func newEventsCollector(lightningClient *client.LightningClient, namespace string) Collector {
+	c := &eventsCollector{
+		lightningClient: lightningClient,
+		invoices: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_invoices_total",
+			Help:      "Number of invoice events received from the invoices stream",
+		}, []string{"state"}),
+		invoicesValue: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_invoices_satoshis_total",
+			Help:      "Value of the invoice events received from the invoices stream",
+		}, []string{"state"}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_transactions_total",
+			Help:      "Number of confirmed wallet transactions received from the transactions stream",
+		}, []string{"direction"}),
+		transactionFees: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_transactions_fees_satoshis_total",
+			Help:      "Fees of the confirmed wallet transactions received from the transactions stream",
+		}),
+		graphUpdates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_graph_updates_total",
+			Help:      "Number of network graph updates received from the channel graph stream",
+		}, []string{"type"}),
+		streamUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "events_stream_up",
+			Help:      "Whether the event stream is open",
+		}, []string{"stream"}),
+		streamRestarts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_stream_restarts_total",
+			Help:      "Number of times the event stream was reopened after failing",
+		}, []string{"stream"}),
+		open: map[string]bool{},
+	}
+	for _, stream := range eventStreams {
+		c.setOpen(stream, false)
+	}
+
+	return c
+}
+
+// Start opens the streams in the background, keeping them open until the
+// context is done.
+func (c *eventsCollector) Start(ctx context.Context) {
+	go c.keepStreaming(ctx, "invoices", func(opened func()) error {
+		return c.lightningClient.SubscribeInvoices(ctx, c.addIndex, c.settleIndex, opened, c.handleInvoice)
+	})
+	go c.keepStreaming(ctx, "transactions", func(opened func()) error {
+		return c.lightningClient.SubscribeTransactions(ctx, opened, c.handleTransaction)
+	})
+	go c.keepStreaming(ctx, "graph", func(opened func()) error {
+		return c.lightningClient.SubscribeChannelGraph(ctx, opened, c.handleGraphUpdate)
+	})
+}
+
+func (c *eventsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.invoices.Describe(ch)
+	c.invoicesValue.Describe(ch)
+	c.transactions.Describe(ch)
+	c.transactionFees.Describe(ch)
+	c.graphUpdates.Describe(ch)
+	c.streamUp.Describe(ch)
+	c.streamRestarts.Describe(ch)
+}
+
+func (c *eventsCollector) Update(ch chan<- prometheus.Metric) error {
+	c.invoices.Collect(ch)
+	c.invoicesValue.Collect(ch)
+	c.transactions.Collect(ch)
+	c.transactionFees.Collect(ch)
+	c.graphUpdates.Collect(ch)
+	c.streamUp.Collect(ch)
+	c.streamRestarts.Collect(ch)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	down := []string{}
+	for _, stream := range eventStreams {
+		if !c.open[stream] {
+			down = append(down, stream)
+		}
+	}
+	if len(down) > 0 {
+		return fmt.Errorf("events streams not open: %s", strings.Join(down, ", "))
+	}
+
+	return nil
+}
+
+// setOpen records whether the stream is open.
+func (c *eventsCollector) setOpen(stream string, open bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.open[stream] = open
+	c.streamUp.WithLabelValues(stream).Set(float64(boolToInt(open)))
+}
+
+// keepStreaming opens the stream of the events collector with keepStreaming,
+// recording whether it is open and counting its restarts.
+func (c *eventsCollector) keepStreaming(ctx context.Context, stream string, subscribe func(opened func()) error) {
+	keepStreaming(ctx, stream+" events", subscribe,
+		func(open bool) { c.setOpen(stream, open) },
+		func() { c.streamRestarts.WithLabelValues(stream).Inc() })
+}
+
+// keepStreaming runs subscribe and runs it again whenever it fails, waiting
+// an exponential backoff between attempts, until the context is done. The
+// stream is reported open to setOpen from the moment subscribe calls opened
+// until it returns, and restarted is called before every new attempt. The
+// backoff is reset once a stream stayed open longer than the maximum backoff.
+func keepStreaming(ctx context.Context, stream string, subscribe func(opened func()) error, setOpen func(bool), restarted func()) {
+	backoff := streamMinBackoff
+	for {
+		start := time.Now()
+		err := subscribe(func() { setOpen(true) })
+		setOpen(false)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Error in %s stream: %v", stream, err)
+
+		if time.Since(start) > streamMaxBackoff {
+			backoff = streamMinBackoff
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		if backoff *= 2; backoff > streamMaxBackoff {
+			backoff = streamMaxBackoff
+		}
+		restarted()
+	}
+}
+
+// handleInvoice counts an invoice as created when its add_index is above the
+// last one seen, and as settled when it is settled and its settle_index is
+// above the last one seen. On reconnect, lnd replays the invoices added since
+// add_index with their current state, so a single notification can count the
+// invoice both as created and settled, and the replayed settlements are not
+// counted again.
+func (c *eventsCollector) handleInvoice(invoice client.InvoiceStats) {
+	if invoice.AddIndex > c.addIndex {
+		c.addIndex = invoice.AddIndex
+		c.invoices.WithLabelValues("created").Inc()
+		c.invoicesValue.WithLabelValues("created").Add(float64(invoice.Value))
+	}
+
+	if invoice.Settled && invoice.SettleIndex > c.settleIndex {
+		c.settleIndex = invoice.SettleIndex
+		c.invoices.WithLabelValues("settled").Inc()
+		c.invoicesValue.WithLabelValues("settled").Add(float64(invoice.AmtPaidSat))
+	}
+}
+
+// handleTransaction counts a transaction when it confirms. The notification
+// sent while it was unconfirmed is ignored, so a transaction sent on the
+// stream twice is counted once.
+func (c *eventsCollector) handleTransaction(transaction client.TransactionStats) {
+	if transaction.NumConfirmations == 0 {
+		return
+	}
+
+	direction := "received"
+	if transaction.Amount < 0 {
+		direction = "sent"
+	}
+	c.transactions.WithLabelValues(direction).Inc()
+	c.transactionFees.Add(float64(transaction.TotalFees))
+}
+
+func (c *eventsCollector) handleGraphUpdate(update client.GraphUpdateStats) {
+	c.graphUpdates.WithLabelValues("node").Add(float64(update.NodeUpdates))
+	c.graphUpdates.WithLabelValues("channel").Add(float64(update.ChannelUpdates))
+	c.graphUpdates.WithLabelValues("closed_channel").Add(float64(update.ClosedChannels))
+}
@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// updateEvents updates the collector, discarding its metrics.
+func updateEvents(c Collector) error {
+	ch := make(chan prometheus.Metric, 100)
+	defer close(ch)
+
+	return c.Update(ch)
+}
+
+func TestEventsStreams(t *testing.T) {
+	node := &fakeLightningClient{
+		invoiceEvents:     newFakeStream(),
+		transactionEvents: newFakeStream(),
+		graphEvents:       newFakeStream(),
+	}
+	events := newEventsCollector(client.NewLightningClient(node), "lnd").(*eventsCollector)
+
+	if err := updateEvents(events); err == nil {
+		t.Error("expected the scrape to fail before the streams are started")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events.Start(ctx)
+	for _, stream := range []*fakeStream{node.invoiceEvents, node.transactionEvents, node.graphEvents} {
+		stream.waitDrained(t, 1)
+	}
+	if err := updateEvents(events); err != nil {
+		t.Errorf("expected the scrape to succeed with the streams open, got %v", err)
+	}
+
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for updateEvents(events) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the event streams to stop")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// counterValue returns the value of the counter with the given label value.
+func counterValue(t *testing.T, vec *prometheus.CounterVec, label string) float64 {
+	metric := &dto.Metric{}
+	if err := vec.WithLabelValues(label).Write(metric); err != nil {
+		t.Fatalf("writing metric failed: %v", err)
+	}
+	return metric.Counter.GetValue()
+}
+
+func TestEventsInvoicesReplayed(t *testing.T) {
+	events := newEventsCollector(client.NewLightningClient(nil), "lnd").(*eventsCollector)
+
+	notifications := []client.InvoiceStats{
+		{AddIndex: 1, Value: 1000},
+		{AddIndex: 1, SettleIndex: 1, Settled: true, AmtPaidSat: 1000},
+		// The stream reconnects from add_index 1 and settle_index 1, and
+		// the invoice added and settled meanwhile is replayed from both
+		// backlogs.
+		{AddIndex: 2, SettleIndex: 2, Settled: true, Value: 2000, AmtPaidSat: 2000},
+		{AddIndex: 2, SettleIndex: 2, Settled: true, Value: 2000, AmtPaidSat: 2000},
+		// A later reconnect replays it again.
+		{AddIndex: 2, SettleIndex: 2, Settled: true, Value: 2000, AmtPaidSat: 2000},
+	}
+	for _, invoice := range notifications {
+		events.handleInvoice(invoice)
+	}
+
+	expected := map[string]float64{"created": 2, "settled": 2}
+	for state, value := range expected {
+		if got := counterValue(t, events.invoices, state); got != value {
+			t.Errorf("expected %v %s invoices, got %v", value, state, got)
+		}
+	}
+	if got := counterValue(t, events.invoicesValue, "created"); got != 3000 {
+		t.Errorf("expected 3000 created satoshis, got %v", got)
+	}
+	if events.addIndex != 2 || events.settleIndex != 2 {
+		t.Errorf("expected the stream to resume from indexes 2 and 2, got %d and %d", events.addIndex, events.settleIndex)
+	}
+}
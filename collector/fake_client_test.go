@@ -0,0 +1,204 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"google.golang.org/grpc"
+)
+
+// fakeLightningClient serves fixed responses to the rpc calls made by the
+// sub-collectors. The embedded interface makes the calls no sub-collector
+// makes panic.
+type fakeLightningClient struct {
+	lnrpc.LightningClient
+
+	info            *lnrpc.GetInfoResponse
+	walletBalance   *lnrpc.WalletBalanceResponse
+	channelBalance  *lnrpc.ChannelBalanceResponse
+	pendingChannels *lnrpc.PendingChannelsResponse
+	channels        *lnrpc.ListChannelsResponse
+	closedChannels  *lnrpc.ClosedChannelsResponse
+	forwarding      []*lnrpc.ForwardingEvent
+	feeReport       *lnrpc.FeeReportResponse
+	peers           *lnrpc.ListPeersResponse
+	networkInfo     *lnrpc.NetworkInfo
+	invoices        []*lnrpc.Invoice
+	payments        *lnrpc.ListPaymentsResponse
+
+	invoiceEvents     *fakeStream
+	transactionEvents *fakeStream
+	graphEvents       *fakeStream
+
+	infoCalls      int
+	invoiceOffsets []uint64
+}
+
+func (f *fakeLightningClient) GetInfo(ctx context.Context, in *lnrpc.GetInfoRequest, opts ...grpc.CallOption) (*lnrpc.GetInfoResponse, error) {
+	f.infoCalls++
+	return f.info, nil
+}
+
+func (f *fakeLightningClient) WalletBalance(ctx context.Context, in *lnrpc.WalletBalanceRequest, opts ...grpc.CallOption) (*lnrpc.WalletBalanceResponse, error) {
+	return f.walletBalance, nil
+}
+
+func (f *fakeLightningClient) ChannelBalance(ctx context.Context, in *lnrpc.ChannelBalanceRequest, opts ...grpc.CallOption) (*lnrpc.ChannelBalanceResponse, error) {
+	return f.channelBalance, nil
+}
+
+func (f *fakeLightningClient) PendingChannels(ctx context.Context, in *lnrpc.PendingChannelsRequest, opts ...grpc.CallOption) (*lnrpc.PendingChannelsResponse, error) {
+	return f.pendingChannels, nil
+}
+
+func (f *fakeLightningClient) ListChannels(ctx context.Context, in *lnrpc.ListChannelsRequest, opts ...grpc.CallOption) (*lnrpc.ListChannelsResponse, error) {
+	return f.channels, nil
+}
+
+func (f *fakeLightningClient) ClosedChannels(ctx context.Context, in *lnrpc.ClosedChannelsRequest, opts ...grpc.CallOption) (*lnrpc.ClosedChannelsResponse, error) {
+	return f.closedChannels, nil
+}
+
+func (f *fakeLightningClient) ForwardingHistory(ctx context.Context, in *lnrpc.ForwardingHistoryRequest, opts ...grpc.CallOption) (*lnrpc.ForwardingHistoryResponse, error) {
+	events := []*lnrpc.ForwardingEvent{}
+	if int(in.IndexOffset) < len(f.forwarding) {
+		events = f.forwarding[in.IndexOffset:]
+	}
+
+	return &lnrpc.ForwardingHistoryResponse{
+		ForwardingEvents: events,
+		LastOffsetIndex:  uint32(len(f.forwarding)),
+	}, nil
+}
+
+func (f *fakeLightningClient) FeeReport(ctx context.Context, in *lnrpc.FeeReportRequest, opts ...grpc.CallOption) (*lnrpc.FeeReportResponse, error) {
+	return f.feeReport, nil
+}
+
+func (f *fakeLightningClient) ListPeers(ctx context.Context, in *lnrpc.ListPeersRequest, opts ...grpc.CallOption) (*lnrpc.ListPeersResponse, error) {
+	return f.peers, nil
+}
+
+func (f *fakeLightningClient) GetNetworkInfo(ctx context.Context, in *lnrpc.NetworkInfoRequest, opts ...grpc.CallOption) (*lnrpc.NetworkInfo, error) {
+	return f.networkInfo, nil
+}
+
+func (f *fakeLightningClient) ListInvoices(ctx context.Context, in *lnrpc.ListInvoiceRequest, opts ...grpc.CallOption) (*lnrpc.ListInvoiceResponse, error) {
+	f.invoiceOffsets = append(f.invoiceOffsets, in.IndexOffset)
+	invoices := []*lnrpc.Invoice{}
+	for _, invoice := range f.invoices {
+		if invoice.AddIndex > in.IndexOffset {
+			invoices = append(invoices, invoice)
+		}
+	}
+
+	return &lnrpc.ListInvoiceResponse{
+		Invoices:        invoices,
+		LastIndexOffset: uint64(len(f.invoices)),
+	}, nil
+}
+
+func (f *fakeLightningClient) ListPayments(ctx context.Context, in *lnrpc.ListPaymentsRequest, opts ...grpc.CallOption) (*lnrpc.ListPaymentsResponse, error) {
+	return f.payments, nil
+}
+
+func (f *fakeLightningClient) SubscribeInvoices(ctx context.Context, in *lnrpc.InvoiceSubscription, opts ...grpc.CallOption) (lnrpc.Lightning_SubscribeInvoicesClient, error) {
+	return fakeInvoiceStreamClient{f.invoiceEvents.open(ctx)}, nil
+}
+
+func (f *fakeLightningClient) SubscribeTransactions(ctx context.Context, in *lnrpc.GetTransactionsRequest, opts ...grpc.CallOption) (lnrpc.Lightning_SubscribeTransactionsClient, error) {
+	return fakeTransactionStreamClient{f.transactionEvents.open(ctx)}, nil
+}
+
+func (f *fakeLightningClient) SubscribeChannelGraph(ctx context.Context, in *lnrpc.GraphTopologySubscription, opts ...grpc.CallOption) (lnrpc.Lightning_SubscribeChannelGraphClient, error) {
+	return fakeGraphStreamClient{f.graphEvents.open(ctx)}, nil
+}
+
+// fakeStream delivers its events to every subscriber and then blocks until
+// the context of the subscription is done. A value is sent on the drained
+// channel each time a subscriber handled every event, that is, when it reads
+// the stream again after the last one.
+type fakeStream struct {
+	events  []interface{}
+	drained chan struct{}
+}
+
+func newFakeStream(events ...interface{}) *fakeStream {
+	return &fakeStream{
+		events:  events,
+		drained: make(chan struct{}, 10),
+	}
+}
+
+// waitDrained waits until the given number of subscribers handled every event
+// of the stream.
+func (s *fakeStream) waitDrained(t *testing.T, subscribers int) {
+	for i := 0; i < subscribers; i++ {
+		select {
+		case <-s.drained:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the event streams")
+		}
+	}
+}
+
+func (s *fakeStream) open(ctx context.Context) *fakeStreamClient {
+	return &fakeStreamClient{stream: s, ctx: ctx}
+}
+
+type fakeStreamClient struct {
+	grpc.ClientStream
+
+	stream  *fakeStream
+	ctx     context.Context
+	next    int
+	drained bool
+}
+
+func (c *fakeStreamClient) recv() (interface{}, error) {
+	if c.next < len(c.stream.events) {
+		c.next++
+		return c.stream.events[c.next-1], nil
+	}
+
+	if !c.drained {
+		c.drained = true
+		c.stream.drained <- struct{}{}
+	}
+	<-c.ctx.Done()
+	return nil, c.ctx.Err()
+}
+
+// fakeInvoiceStreamClient, fakeTransactionStreamClient and
+// fakeGraphStreamClient give the stream the Recv method of each subscription.
+type fakeInvoiceStreamClient struct{ *fakeStreamClient }
+
+func (c fakeInvoiceStreamClient) Recv() (*lnrpc.Invoice, error) {
+	event, err := c.recv()
+	if err != nil {
+		return nil, err
+	}
+	return event.(*lnrpc.Invoice), nil
+}
+
+type fakeTransactionStreamClient struct{ *fakeStreamClient }
+
+func (c fakeTransactionStreamClient) Recv() (*lnrpc.Transaction, error) {
+	event, err := c.recv()
+	if err != nil {
+		return nil, err
+	}
+	return event.(*lnrpc.Transaction), nil
+}
+
+type fakeGraphStreamClient struct{ *fakeStreamClient }
+
+func (c fakeGraphStreamClient) Recv() (*lnrpc.GraphTopologyUpdate, error) {
+	event, err := c.recv()
+	if err != nil {
+		return nil, err
+	}
+	return event.(*lnrpc.GraphTopologyUpdate), nil
+}
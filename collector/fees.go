@@ -0,0 +1,56 @@
+package collector
+
+import (
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("fees", "fee_report", true, newFeesCollector)
+}
+
+type feesCollector struct {
+	lightningClient *client.LightningClient
+	metrics         map[string]*prometheus.Desc
+}
+
+func newFeesCollector(lightningClient *client.LightningClient, namespace string) Collector {
+	return &feesCollector{
+		lightningClient: lightningClient,
+		metrics: map[string]*prometheus.Desc{
+			"fee_sum_satoshis":      newGlobalMetric(namespace, "fee_sum_satoshis", "Sum of the fees earned forwarding payments over the period", []string{"period"}),
+			"channel_base_fee_msat": newGlobalMetric(namespace, "channel_base_fee_msat", "The base fee charged for forwarding through the channel", []string{"channel_point"}),
+			"channel_fee_per_mil":   newGlobalMetric(namespace, "channel_fee_per_mil", "The fee charged per million satoshis forwarded through the channel", []string{"channel_point"}),
+			"channel_fee_rate":      newGlobalMetric(namespace, "channel_fee_rate", "The fee rate charged for forwarding through the channel", []string{"channel_point"}),
+		},
+	}
+}
+
+func (c *feesCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeMetrics(ch, c.metrics)
+}
+
+func (c *feesCollector) Update(ch chan<- prometheus.Metric) error {
+	feeReportStats, err := c.lightningClient.GetFeeReportStats()
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.metrics["fee_sum_satoshis"],
+		prometheus.GaugeValue, float64(feeReportStats.DayFeeSum), "day")
+	ch <- prometheus.MustNewConstMetric(c.metrics["fee_sum_satoshis"],
+		prometheus.GaugeValue, float64(feeReportStats.WeekFeeSum), "week")
+	ch <- prometheus.MustNewConstMetric(c.metrics["fee_sum_satoshis"],
+		prometheus.GaugeValue, float64(feeReportStats.MonthFeeSum), "month")
+
+	for _, channel := range feeReportStats.ChannelFees {
+		ch <- prometheus.MustNewConstMetric(c.metrics["channel_base_fee_msat"],
+			prometheus.GaugeValue, float64(channel.BaseFeeMsat), channel.ChannelPoint)
+		ch <- prometheus.MustNewConstMetric(c.metrics["channel_fee_per_mil"],
+			prometheus.GaugeValue, float64(channel.FeePerMil), channel.ChannelPoint)
+		ch <- prometheus.MustNewConstMetric(c.metrics["channel_fee_rate"],
+			prometheus.GaugeValue, channel.FeeRate, channel.ChannelPoint)
+	}
+
+	return nil
+}
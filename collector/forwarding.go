@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"strconv"
+
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// forwardingPageSize is the number of forwarding events requested per page.
+const forwardingPageSize = 1000
+
+var forwardingLabels = []string{"chan_id_in", "chan_id_out"}
+
+func init() {
+	registerCollector("forwarding", "forwarding_history", true, newForwardingCollector)
+}
+
+type forwardingRoute struct {
+	chanIDIn  uint64
+	chanIDOut uint64
+}
+
+type forwardingTotals struct {
+	forwards  uint64
+	amountIn  uint64
+	amountOut uint64
+	fees      uint64
+}
+
+// forwardingCollector keeps the totals of the forwarding events read so far
+// and the offset to resume from, so every scrape only pages through the
+// events added since the previous one.
+type forwardingCollector struct {
+	lightningClient *client.LightningClient
+	metrics         map[string]*prometheus.Desc
+	indexOffset     uint32
+	totals          map[forwardingRoute]*forwardingTotals
+}
+
+func newForwardingCollector(lightningClient *client.LightningClient, namespace string) Collector {
+	return &forwardingCollector{
+		lightningClient: lightningClient,
+		totals:          map[forwardingRoute]*forwardingTotals{},
+		metrics: map[string]*prometheus.Desc{
+			"forwards_total":                    newGlobalMetric(namespace, "forwards_total", "Number of payments forwarded", forwardingLabels),
+			"forward_amount_in_satoshis_total":  newGlobalMetric(namespace, "forward_amount_in_satoshis_total", "Amount received by the incoming channel of the forwarded payments", forwardingLabels),
+			"forward_amount_out_satoshis_total": newGlobalMetric(namespace, "forward_amount_out_satoshis_total", "Amount sent through the outgoing channel of the forwarded payments", forwardingLabels),
+			"forward_fees_satoshis_total":       newGlobalMetric(namespace, "forward_fees_satoshis_total", "Fees earned by forwarding payments", forwardingLabels),
+		},
+	}
+}
+
+func (c *forwardingCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeMetrics(ch, c.metrics)
+}
+
+func (c *forwardingCollector) Update(ch chan<- prometheus.Metric) error {
+	for {
+		events, lastOffset, err := c.lightningClient.GetForwardingHistory(c.indexOffset, forwardingPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			route := forwardingRoute{chanIDIn: event.ChanIDIn, chanIDOut: event.ChanIDOut}
+			totals, ok := c.totals[route]
+			if !ok {
+				totals = &forwardingTotals{}
+				c.totals[route] = totals
+			}
+			totals.forwards++
+			totals.amountIn += event.AmountIn
+			totals.amountOut += event.AmountOut
+			totals.fees += event.Fee
+		}
+		if len(events) > 0 {
+			c.indexOffset = lastOffset
+		}
+
+		if len(events) < forwardingPageSize {
+			break
+		}
+	}
+
+	for route, totals := range c.totals {
+		labels := []string{
+			strconv.FormatUint(route.chanIDIn, 10),
+			strconv.FormatUint(route.chanIDOut, 10),
+		}
+		ch <- prometheus.MustNewConstMetric(c.metrics["forwards_total"],
+			prometheus.CounterValue, float64(totals.forwards), labels...)
+		ch <- prometheus.MustNewConstMetric(c.metrics["forward_amount_in_satoshis_total"],
+			prometheus.CounterValue, float64(totals.amountIn), labels...)
+		ch <- prometheus.MustNewConstMetric(c.metrics["forward_amount_out_satoshis_total"],
+			prometheus.CounterValue, float64(totals.amountOut), labels...)
+		ch <- prometheus.MustNewConstMetric(c.metrics["forward_fees_satoshis_total"],
+			prometheus.CounterValue, float64(totals.fees), labels...)
+	}
+
+	return nil
+}
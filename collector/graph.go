@@ -0,0 +1,81 @@
+package collector
+
+import (
+	"flag"
+	"time"
+
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var graphInterval = flag.Duration("collector.graph.interval", 5*time.Minute,
+	"How often the graph collector refreshes the network graph statistics.")
+
+func init() {
+	registerCollector("graph", "get_network_info", false, newGraphCollector)
+}
+
+// graphCollector caches the network graph statistics, as they are expensive
+// to compute, and only refreshes them once the interval has elapsed.
+type graphCollector struct {
+	lightningClient *client.LightningClient
+	metrics         map[string]*prometheus.Desc
+	interval        time.Duration
+	networkStats    *client.NetworkStats
+	lastUpdate      time.Time
+}
+
+func newGraphCollector(lightningClient *client.LightningClient, namespace string) Collector {
+	return &graphCollector{
+		lightningClient: lightningClient,
+		interval:        *graphInterval,
+		metrics: map[string]*prometheus.Desc{
+			"graph_diameter":              newGlobalMetric(namespace, "graph_diameter", "The diameter of the network graph", []string{}),
+			"graph_avg_out_degree":        newGlobalMetric(namespace, "graph_avg_out_degree", "The average number of channels per node", []string{}),
+			"graph_max_out_degree":        newGlobalMetric(namespace, "graph_max_out_degree", "The maximum number of channels of a node", []string{}),
+			"graph_nodes":                 newGlobalMetric(namespace, "graph_nodes", "Number of nodes in the network graph", []string{}),
+			"graph_channels":              newGlobalMetric(namespace, "graph_channels", "Number of channels in the network graph", []string{}),
+			"graph_capacity_satoshis":     newGlobalMetric(namespace, "graph_capacity_satoshis", "Total capacity of the channels in the network graph", []string{}),
+			"graph_channel_size_satoshis": newGlobalMetric(namespace, "graph_channel_size_satoshis", "Capacity of the channels in the network graph", []string{"stat"}),
+			"graph_last_update_timestamp": newGlobalMetric(namespace, "graph_last_update_timestamp", "Unix time at which the network graph statistics were fetched", []string{}),
+		},
+	}
+}
+
+func (c *graphCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeMetrics(ch, c.metrics)
+}
+
+func (c *graphCollector) Update(ch chan<- prometheus.Metric) error {
+	if c.networkStats == nil || time.Since(c.lastUpdate) >= c.interval {
+		networkStats, err := c.lightningClient.GetNetworkStats()
+		if err != nil {
+			return err
+		}
+		c.networkStats = networkStats
+		c.lastUpdate = time.Now()
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.metrics["graph_diameter"],
+		prometheus.GaugeValue, float64(c.networkStats.GraphDiameter))
+	ch <- prometheus.MustNewConstMetric(c.metrics["graph_avg_out_degree"],
+		prometheus.GaugeValue, c.networkStats.AvgOutDegree)
+	ch <- prometheus.MustNewConstMetric(c.metrics["graph_max_out_degree"],
+		prometheus.GaugeValue, float64(c.networkStats.MaxOutDegree))
+	ch <- prometheus.MustNewConstMetric(c.metrics["graph_nodes"],
+		prometheus.GaugeValue, float64(c.networkStats.NumNodes))
+	ch <- prometheus.MustNewConstMetric(c.metrics["graph_channels"],
+		prometheus.GaugeValue, float64(c.networkStats.NumChannels))
+	ch <- prometheus.MustNewConstMetric(c.metrics["graph_capacity_satoshis"],
+		prometheus.GaugeValue, float64(c.networkStats.TotalNetworkCapacity))
+	ch <- prometheus.MustNewConstMetric(c.metrics["graph_channel_size_satoshis"],
+		prometheus.GaugeValue, c.networkStats.AvgChannelSize, "avg")
+	ch <- prometheus.MustNewConstMetric(c.metrics["graph_channel_size_satoshis"],
+		prometheus.GaugeValue, float64(c.networkStats.MinChannelSize), "min")
+	ch <- prometheus.MustNewConstMetric(c.metrics["graph_channel_size_satoshis"],
+		prometheus.GaugeValue, float64(c.networkStats.MaxChannelSize), "max")
+	ch <- prometheus.MustNewConstMetric(c.metrics["graph_last_update_timestamp"],
+		prometheus.GaugeValue, float64(c.lastUpdate.Unix()))
+
+	return nil
+}
@@ -5,3 +5,35 @@ import "github.com/prometheus/client_golang/prometheus"
 func newGlobalMetric(namespace string, metricName string, docString string, labels []string) *prometheus.Desc {
 	return prometheus.NewDesc(namespace+"_"+metricName, docString, labels, nil)
 }
+
+func describeMetrics(ch chan<- *prometheus.Desc, metrics map[string]*prometheus.Desc) {
+	for _, m := range metrics {
+		ch <- m
+	}
+}
+
+// newConstHistogram builds a histogram of the given observations.
+func newConstHistogram(desc *prometheus.Desc, buckets []float64, observations []float64) prometheus.Metric {
+	counts := make(map[float64]uint64, len(buckets))
+	sum := 0.0
+	for _, bucket := range buckets {
+		counts[bucket] = 0
+	}
+	for _, observation := range observations {
+		sum += observation
+		for _, bucket := range buckets {
+			if observation <= bucket {
+				counts[bucket]++
+			}
+		}
+	}
+
+	return prometheus.MustNewConstHistogram(desc, uint64(len(observations)), sum, counts)
+}
+
+func boolToInt(arg bool) uint8 {
+	if arg {
+		return 1
+	}
+	return 0
+}
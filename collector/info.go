@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("info", "get_info", true, newInfoCollector)
+}
+
+type infoCollector struct {
+	lightningClient *client.LightningClient
+	metrics         map[string]*prometheus.Desc
+}
+
+func newInfoCollector(lightningClient *client.LightningClient, namespace string) Collector {
+	return &infoCollector{
+		lightningClient: lightningClient,
+		metrics: map[string]*prometheus.Desc{
+			"peers":                     newGlobalMetric(namespace, "peers", "Number of currently connected peers.", []string{}),
+			"channels":                  newGlobalMetric(namespace, "channels", "Number of channels", []string{"status"}),
+			"block_height":              newGlobalMetric(namespace, "block_height", "The node’s current view of the height of the best block", []string{}),
+			"node_info":                 newGlobalMetric(namespace, "node_info", "Information about the lightning node, with a constant value of 1. Chains and uris are comma separated", []string{"identity_pubkey", "alias", "version", "chains", "testnet", "uris"}),
+			"best_header_timestamp":     newGlobalMetric(namespace, "best_header_timestamp", "Unix time of the best block header known to the node", []string{}),
+			"seconds_since_best_header": newGlobalMetric(namespace, "seconds_since_best_header", "Number of seconds since the timestamp of the best block header known to the node", []string{}),
+			"synced_to_chain":           newGlobalMetric(namespace, "synced_to_chain", "Whether the node is synced to the chain", []string{}),
+		},
+	}
+}
+
+func (c *infoCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeMetrics(ch, c.metrics)
+}
+
+func (c *infoCollector) Update(ch chan<- prometheus.Metric) error {
+	nodeStats, err := c.lightningClient.GetInfoStats()
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.metrics["peers"],
+		prometheus.GaugeValue, float64(nodeStats.Peers))
+	ch <- prometheus.MustNewConstMetric(c.metrics["channels"],
+		prometheus.GaugeValue, float64(nodeStats.ActiveChannels), "active")
+	ch <- prometheus.MustNewConstMetric(c.metrics["channels"],
+		prometheus.GaugeValue, float64(nodeStats.PendingChannels), "pending")
+	ch <- prometheus.MustNewConstMetric(c.metrics["channels"],
+		prometheus.GaugeValue, float64(nodeStats.InactiveChannels), "inactive")
+	ch <- prometheus.MustNewConstMetric(c.metrics["block_height"],
+		prometheus.GaugeValue, float64(nodeStats.BlockHeight))
+	ch <- prometheus.MustNewConstMetric(c.metrics["synced_to_chain"],
+		prometheus.GaugeValue, float64(nodeStats.SyncedToChain))
+	ch <- prometheus.MustNewConstMetric(c.metrics["node_info"],
+		prometheus.GaugeValue, 1, nodeStats.IdentityPubkey, nodeStats.Alias, nodeStats.Version,
+		strings.Join(nodeStats.Chains, ","), strconv.FormatBool(nodeStats.Testnet), strings.Join(nodeStats.Uris, ","))
+	ch <- prometheus.MustNewConstMetric(c.metrics["best_header_timestamp"],
+		prometheus.GaugeValue, float64(nodeStats.BestHeaderTimestamp))
+	ch <- prometheus.MustNewConstMetric(c.metrics["seconds_since_best_header"],
+		prometheus.GaugeValue, time.Since(time.Unix(nodeStats.BestHeaderTimestamp, 0)).Seconds())
+
+	return nil
+}
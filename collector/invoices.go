@@ -0,0 +1,199 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// invoicesPageSize is the number of invoices requested per page.
+const invoicesPageSize = 1000
+
+func init() {
+	registerCollector("invoices", "list_invoices", true, newInvoicesCollector)
+}
+
+type invoicesTotals struct {
+	invoices uint64
+	satoshis int64
+}
+
+// pendingInvoice is an invoice that was neither settled nor expired when it
+// was read.
+type pendingInvoice struct {
+	value        int64
+	creationDate int64
+	expiry       int64
+}
+
+// invoicesCollector keeps the totals of the invoices processed so far. Every
+// scrape only reads the invoices added since the add_index of the last one
+// read. The invoices still pending are kept with their expiry, so they are
+// counted as expired without reading them again, and a SubscribeInvoices
+// stream resuming from the last settle_index seen reports their settlement.
+// An invoice is counted as settled or expired when it leaves the pending set,
+// so it is counted once.
+type invoicesCollector struct {
+	lightningClient *client.LightningClient
+	metrics         map[string]*prometheus.Desc
+	settleDuration  prometheus.Histogram
+	streamUp        prometheus.Gauge
+	addIndex        uint64
+	settleIndex     uint64
+	pending         map[uint64]pendingInvoice
+	created         invoicesTotals
+	settled         invoicesTotals
+	expired         invoicesTotals
+	mutex           sync.Mutex
+}
+
+func newInvoicesCollector(lightningClient *client.LightningClient, namespace string) Collector {
+	return &invoicesCollector{
+		lightningClient: lightningClient,
+		pending:         map[uint64]pendingInvoice{},
+		settleDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "invoice_settle_duration_seconds",
+			Help:      "Time elapsed between the creation and the settlement of the invoices",
+			Buckets:   []float64{1, 5, 10, 30, 60, 300, 600, 1800, 3600, 86400},
+		}),
+		streamUp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "invoices_settlements_stream_up",
+			Help:      "Whether the stream reporting the settlement of the pending invoices is open",
+		}),
+		metrics: map[string]*prometheus.Desc{
+			"invoices_total":          newGlobalMetric(namespace, "invoices_total", "Number of invoices", []string{"state"}),
+			"invoices_satoshis_total": newGlobalMetric(namespace, "invoices_satoshis_total", "Value of the invoices", []string{"state"}),
+			"invoices_add_index":      newGlobalMetric(namespace, "invoices_add_index", "The add_index of the last invoice created", []string{}),
+			"invoices_settle_index":   newGlobalMetric(namespace, "invoices_settle_index", "The settle_index of the last invoice settled", []string{}),
+			"invoices_pending":        newGlobalMetric(namespace, "invoices_pending", "Number of invoices neither settled nor expired", []string{}),
+		},
+	}
+}
+
+// Start opens the stream of settlements in the background, keeping it open
+// until the context is done.
+func (c *invoicesCollector) Start(ctx context.Context) {
+	go keepStreaming(ctx, "invoice settlements", func(opened func()) error {
+		c.mutex.Lock()
+		settleIndex := c.settleIndex
+		c.mutex.Unlock()
+
+		return c.lightningClient.SubscribeInvoices(ctx, 0, settleIndex, opened, c.handleInvoice)
+	}, func(open bool) {
+		c.streamUp.Set(float64(boolToInt(open)))
+	}, func() {})
+}
+
+func (c *invoicesCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeMetrics(ch, c.metrics)
+	ch <- c.settleDuration.Desc()
+	ch <- c.streamUp.Desc()
+}
+
+func (c *invoicesCollector) Update(ch chan<- prometheus.Metric) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for {
+		invoices, _, err := c.lightningClient.GetInvoices(c.addIndex, invoicesPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, invoice := range invoices {
+			c.processInvoice(invoice)
+		}
+
+		if len(invoices) < invoicesPageSize {
+			break
+		}
+	}
+	c.expirePending(time.Now().Unix())
+
+	for state, totals := range map[string]invoicesTotals{
+		"created": c.created,
+		"settled": c.settled,
+		"expired": c.expired,
+	} {
+		ch <- prometheus.MustNewConstMetric(c.metrics["invoices_total"],
+			prometheus.CounterValue, float64(totals.invoices), state)
+		ch <- prometheus.MustNewConstMetric(c.metrics["invoices_satoshis_total"],
+			prometheus.CounterValue, float64(totals.satoshis), state)
+	}
+	ch <- prometheus.MustNewConstMetric(c.metrics["invoices_add_index"],
+		prometheus.GaugeValue, float64(c.addIndex))
+	ch <- prometheus.MustNewConstMetric(c.metrics["invoices_settle_index"],
+		prometheus.GaugeValue, float64(c.settleIndex))
+	ch <- prometheus.MustNewConstMetric(c.metrics["invoices_pending"],
+		prometheus.GaugeValue, float64(len(c.pending)))
+	ch <- c.settleDuration
+	ch <- c.streamUp
+
+	return nil
+}
+
+// processInvoice counts an invoice read for the first time as created, and as
+// settled when it already is. Otherwise it is kept as pending.
+func (c *invoicesCollector) processInvoice(invoice client.InvoiceStats) {
+	if invoice.AddIndex <= c.addIndex {
+		return
+	}
+	c.addIndex = invoice.AddIndex
+	c.created.invoices++
+	c.created.satoshis += invoice.Value
+
+	if invoice.Settled {
+		c.settle(invoice)
+		return
+	}
+	c.pending[invoice.AddIndex] = pendingInvoice{
+		value:        invoice.Value,
+		creationDate: invoice.CreationDate,
+		expiry:       invoice.Expiry,
+	}
+}
+
+// handleInvoice counts the settlement of a pending invoice received from the
+// stream. Invoices that were not read yet are counted by the next scrape, and
+// the other notifications of the stream are ignored.
+func (c *invoicesCollector) handleInvoice(invoice client.InvoiceStats) {
+	if !invoice.Settled {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.pending[invoice.AddIndex]; ok {
+		delete(c.pending, invoice.AddIndex)
+		c.settle(invoice)
+	} else if invoice.SettleIndex > c.settleIndex {
+		c.settleIndex = invoice.SettleIndex
+	}
+}
+
+// settle counts the invoice as settled.
+func (c *invoicesCollector) settle(invoice client.InvoiceStats) {
+	if invoice.SettleIndex > c.settleIndex {
+		c.settleIndex = invoice.SettleIndex
+	}
+	c.settled.invoices++
+	c.settled.satoshis += invoice.AmtPaidSat
+	c.settleDuration.Observe(float64(invoice.SettleDate - invoice.CreationDate))
+}
+
+// expirePending counts the pending invoices past their expiry as expired.
+func (c *invoicesCollector) expirePending(now int64) {
+	for addIndex, invoice := range c.pending {
+		if invoice.creationDate+invoice.expiry < now {
+			delete(c.pending, addIndex)
+			c.expired.invoices++
+			c.expired.satoshis += invoice.value
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// scrapeInvoices updates the collector and returns the invoices_total value
+// of each state.
+func scrapeInvoices(t *testing.T, c Collector) map[string]float64 {
+	ch := make(chan prometheus.Metric, 100)
+	if err := c.Update(ch); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	close(ch)
+
+	totals := map[string]float64{}
+	for m := range ch {
+		if !strings.Contains(m.Desc().String(), `"lnd_invoices_total"`) {
+			continue
+		}
+		metric := &dto.Metric{}
+		if err := m.Write(metric); err != nil {
+			t.Fatalf("writing metric failed: %v", err)
+		}
+		totals[metric.Label[0].GetValue()] = metric.Counter.GetValue()
+	}
+
+	return totals
+}
+
+func TestInvoicesAcrossScrapes(t *testing.T) {
+	now := time.Now().Unix()
+	node := &fakeLightningClient{invoices: []*lnrpc.Invoice{
+		{AddIndex: 1, Value: 1000, CreationDate: now, Expiry: 365 * 86400},
+		{AddIndex: 2, Value: 2000, CreationDate: now, Expiry: 3600},
+		{AddIndex: 3, Value: 3000, CreationDate: now - 7200, Expiry: 3600},
+		{AddIndex: 4, Value: 4000, CreationDate: now - 60, Expiry: 3600, Settled: true, SettleIndex: 1, SettleDate: now, AmtPaidSat: 4000},
+	}}
+	invoices := newInvoicesCollector(client.NewLightningClient(node), "lnd").(*invoicesCollector)
+
+	steps := []struct {
+		name     string
+		change   func()
+		expected map[string]float64
+	}{{
+		name:     "first scrape",
+		change:   func() {},
+		expected: map[string]float64{"created": 4, "settled": 1, "expired": 1},
+	}, {
+		name: "settlements received from the stream",
+		change: func() {
+			invoices.handleInvoice(client.InvoiceStats{AddIndex: 2, Settled: true, SettleIndex: 3, AmtPaidSat: 2000})
+			// Settled before the invoice above but notified later.
+			invoices.handleInvoice(client.InvoiceStats{AddIndex: 1, Settled: true, SettleIndex: 2, AmtPaidSat: 1000})
+			// Replayed on reconnect.
+			invoices.handleInvoice(client.InvoiceStats{AddIndex: 2, Settled: true, SettleIndex: 3, AmtPaidSat: 2000})
+		},
+		expected: map[string]float64{"created": 4, "settled": 3, "expired": 1},
+	}, {
+		name: "invoice added and settled between scrapes",
+		change: func() {
+			settled := &lnrpc.Invoice{AddIndex: 5, Value: 5000, CreationDate: now, Expiry: 3600, Settled: true, SettleIndex: 4, SettleDate: now, AmtPaidSat: 5000}
+			node.invoices = append(node.invoices, settled)
+			invoices.handleInvoice(client.InvoiceStats{AddIndex: 5, Settled: true, SettleIndex: 4, AmtPaidSat: 5000})
+		},
+		expected: map[string]float64{"created": 5, "settled": 4, "expired": 1},
+	}, {
+		name: "invoice added",
+		change: func() {
+			node.invoices = append(node.invoices, &lnrpc.Invoice{AddIndex: 6, Value: 6000, CreationDate: now, Expiry: 3600})
+		},
+		expected: map[string]float64{"created": 6, "settled": 4, "expired": 1},
+	}, {
+		// The expiry is checked from the invoices kept as pending, as if
+		// the scrape happened two hours later.
+		name: "pending invoice expired",
+		change: func() {
+			invoices.mutex.Lock()
+			invoices.expirePending(now + 7200)
+			invoices.mutex.Unlock()
+		},
+		expected: map[string]float64{"created": 6, "settled": 4, "expired": 2},
+	}, {
+		name:     "nothing changed",
+		change:   func() {},
+		expected: map[string]float64{"created": 6, "settled": 4, "expired": 2},
+	}}
+
+	for _, step := range steps {
+		step.change()
+		totals := scrapeInvoices(t, invoices)
+		for state, value := range step.expected {
+			if totals[state] != value {
+				t.Errorf("%s: expected %v %s invoices, got %v", step.name, value, state, totals[state])
+			}
+		}
+	}
+
+	// Every scrape after the first one only reads the invoices added since
+	// the last one read, even while the first invoice is still pending.
+	expectedOffsets := []uint64{0, 4, 4, 5, 6, 6}
+	for i, offset := range expectedOffsets {
+		if i >= len(node.invoiceOffsets) || node.invoiceOffsets[i] != offset {
+			t.Fatalf("expected the scrapes to read from offsets %v, got %v", expectedOffsets, node.invoiceOffsets)
+		}
+	}
+	if invoices.settleIndex != 4 {
+		t.Errorf("expected the stream to resume from settle_index 4, got %d", invoices.settleIndex)
+	}
+}
@@ -1,34 +1,50 @@
 package collector
 
 import (
+	"context"
 	"log"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/platanus/lightning-prometheus-exporter/client"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-// LightningCollector collects node metrics. It implements prometheus.Collector interface.
+// LightningCollector collects node metrics from the enabled sub-collectors.
+// It implements prometheus.Collector interface.
 type LightningCollector struct {
 	lightningClient *client.LightningClient
+	collectors      map[string]Collector
 	metrics         map[string]*prometheus.Desc
+	scrapeErrors    map[string]float64
 	mutex           sync.Mutex
+
+	polling       bool
+	snapshot      []prometheus.Metric
+	snapshotMutex sync.RWMutex
+	lastRefresh   time.Time
 }
 
 // NewLightningCollector creates an LightningCollector.
 func NewLightningCollector(lightningClient *client.LightningClient, namespace string) *LightningCollector {
+	collectors := map[string]Collector{}
+	for _, name := range EnabledCollectors() {
+		collectors[name] = registrations[name].factory(lightningClient, namespace)
+	}
+
 	return &LightningCollector{
 		lightningClient: lightningClient,
+		collectors:      collectors,
+		scrapeErrors:    map[string]float64{},
 		metrics: map[string]*prometheus.Desc{
-			"wallet_balance_satoshis":         newGlobalMetric(namespace, "wallet_balance_satoshis", "The wallet balance.", []string{"status"}),
-			"peers":                           newGlobalMetric(namespace, "peers", "Number of currently connected peers.", []string{}),
-			"channels":                        newGlobalMetric(namespace, "channels", "Number of channels", []string{"status"}),
-			"block_height":                    newGlobalMetric(namespace, "block_height", "The node’s current view of the height of the best block", []string{}),
-			"synced_to_chain":                 newGlobalMetric(namespace, "synced_to_chain", "The node’s current view of the height of the best block", []string{}),
-			"channels_limbo_balance_satoshis": newGlobalMetric(namespace, "channel_limbo_balance_satoshis", "The balance in satoshis encumbered in pending channels", []string{}),
-			"channels_pending":                newGlobalMetric(namespace, "channel_pending", "The total pending channels", []string{"status", "forced"}),
-			"channels_waiting_close":          newGlobalMetric(namespace, "channel_waiting_close", "Channels waiting for closing tx to confirm", []string{}),
-			"channels_balance_satoshis":       newGlobalMetric(namespace, "channels_balance_satoshis", "Sum of all channel funds available", []string{}),
+			"last_successful_refresh_timestamp": newGlobalMetric(namespace, "last_successful_refresh_timestamp", "Unix time of the last background refresh in which the lightning node could be reached", []string{}),
+			"node_state":                        newGlobalMetric(namespace, "node_state", "Whether the lightning node is in the given state: disconnected, wallet_locked or ready", []string{"state"}),
+			"up":                                newGlobalMetric(namespace, "up", "Whether the lightning node could be reached and was ready", []string{}),
+			"exporter_scrape_duration_seconds":  newGlobalMetric(namespace, "exporter_scrape_duration_seconds", "Duration of the rpc call made by the exporter", []string{"rpc"}),
+			"exporter_scrape_success":           newGlobalMetric(namespace, "exporter_scrape_success", "Whether the rpc call made by the exporter succeeded", []string{"rpc"}),
+			"exporter_scrape_errors_total":      newGlobalMetric(namespace, "exporter_scrape_errors_total", "Total number of failed rpc calls made by the exporter", []string{"rpc"}),
+			"scrape_error":                      newGlobalMetric(namespace, "scrape_error", "Whether an error occurred while fetching the rpc stats", []string{"rpc"}),
 		},
 	}
 }
@@ -39,52 +55,139 @@ func (c *LightningCollector) Describe(ch chan<- *prometheus.Desc) {
 	for _, m := range c.metrics {
 		ch <- m
 	}
+	for _, collector := range c.collectors {
+		collector.Describe(ch)
+	}
 }
 
 // Collect fetches metrics from the node and sends them to the provided channel.
+// A failing sub-collector is reported through the scrape_error metric and does
+// not prevent the remaining ones from being collected. When polling, the
+// metrics of the last refresh are sent instead.
 func (c *LightningCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.polling {
+		c.snapshotMutex.RLock()
+		defer c.snapshotMutex.RUnlock()
+
+		for _, m := range c.snapshot {
+			ch <- m
+		}
+		return
+	}
+
 	c.mutex.Lock() // To protect metrics from concurrent collects
 	defer c.mutex.Unlock()
 
-	_, err := c.lightningClient.GetStats()
+	c.update(ch)
+}
+
+// Start runs the sub-collectors that work in the background, such as the
+// event streams, until the context is done.
+func (c *LightningCollector) Start(ctx context.Context) {
+	for _, collector := range c.collectors {
+		if b, ok := collector.(backgroundCollector); ok {
+			b.Start(ctx)
+		}
+	}
+}
+
+// StartPolling refreshes the metrics every interval in the background, so
+// Collect serves the last refresh instead of fetching them from the node. The
+// first refresh is done before returning.
+func (c *LightningCollector) StartPolling(interval time.Duration) {
+	c.polling = true
+	c.refresh()
+
+	go func() {
+		for range time.Tick(interval) {
+			c.refresh()
+		}
+	}()
+}
+
+// refresh runs the sub-collectors and stores their metrics as the snapshot
+// served by Collect.
+func (c *LightningCollector) refresh() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	ch := make(chan prometheus.Metric)
+	done := make(chan []prometheus.Metric)
+	go func() {
+		snapshot := []prometheus.Metric{}
+		for m := range ch {
+			snapshot = append(snapshot, m)
+		}
+		done <- snapshot
+	}()
+
+	if c.update(ch) {
+		c.lastRefresh = time.Now()
+	}
+	close(ch)
+	snapshot := <-done
+
+	if !c.lastRefresh.IsZero() {
+		snapshot = append(snapshot, prometheus.MustNewConstMetric(c.metrics["last_successful_refresh_timestamp"],
+			prometheus.GaugeValue, float64(c.lastRefresh.Unix())))
+	}
+
+	c.snapshotMutex.Lock()
+	c.snapshot = snapshot
+	c.snapshotMutex.Unlock()
+}
+
+// update runs the sub-collectors, sending their metrics to the provided
+// channel. It returns true when the node could be reached, that is, when it
+// is ready, whether or not its sub-collectors succeed, since their failures
+// are reported by scrape_error. The sub-collectors are skipped while the node
+// is not ready, reporting only its state.
+func (c *LightningCollector) update(ch chan<- prometheus.Metric) bool {
+	state := c.lightningClient.State()
+	for _, s := range []string{client.StateDisconnected, client.StateWalletLocked, client.StateReady} {
+		ch <- prometheus.MustNewConstMetric(c.metrics["node_state"],
+			prometheus.GaugeValue, float64(boolToInt(s == state)), s)
+	}
+	if state != client.StateReady {
+		ch <- prometheus.MustNewConstMetric(c.metrics["up"],
+			prometheus.GaugeValue, 0)
+
+		return false
+	}
+
+	names := make([]string, 0, len(c.collectors))
+	for name := range c.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		start := time.Now()
+		err := c.collectors[name].Update(ch)
+		c.sendScrapeMetrics(ch, registrations[name].rpc, start, err)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.metrics["up"],
+		prometheus.GaugeValue, 1)
+
+	return true
+}
+
+// sendScrapeMetrics sends the scrape metrics of the given rpc, started at
+// start, and logs its error, if any.
+func (c *LightningCollector) sendScrapeMetrics(ch chan<- prometheus.Metric, rpc string, start time.Time, err error) {
+	scrapeError := 0.0
 	if err != nil {
-		log.Printf("Error getting stats: %v", err)
-		return
+		log.Printf("Error getting %s stats: %v", rpc, err)
+		scrapeError = 1
+		c.scrapeErrors[rpc]++
 	}
-	walletStats, _ := c.lightningClient.GetWalletStats()
-	nodeStats, _ := c.lightningClient.GetInfoStats()
-	pendingChannelsStats, _ := c.lightningClient.GetPendingChannelsStats()
-	channelBalanceStats, _ := c.lightningClient.GetChannelsBalanceStats()
-
-	ch <- prometheus.MustNewConstMetric(c.metrics["wallet_balance_satoshis"],
-		prometheus.GaugeValue, float64(walletStats.UnconfirmedBalance), "unconfirmed")
-	ch <- prometheus.MustNewConstMetric(c.metrics["wallet_balance_satoshis"],
-		prometheus.GaugeValue, float64(walletStats.ConfirmedBalance), "confirmed")
-
-	ch <- prometheus.MustNewConstMetric(c.metrics["peers"],
-		prometheus.GaugeValue, float64(nodeStats.Peers))
-	ch <- prometheus.MustNewConstMetric(c.metrics["channels"],
-		prometheus.GaugeValue, float64(nodeStats.ActiveChannels), "active")
-	ch <- prometheus.MustNewConstMetric(c.metrics["channels"],
-		prometheus.GaugeValue, float64(nodeStats.PendingChannels), "pending")
-	ch <- prometheus.MustNewConstMetric(c.metrics["channels"],
-		prometheus.GaugeValue, float64(nodeStats.InactiveChannels), "inactive")
-	ch <- prometheus.MustNewConstMetric(c.metrics["block_height"],
-		prometheus.GaugeValue, float64(nodeStats.BlockHeight))
-	ch <- prometheus.MustNewConstMetric(c.metrics["synced_to_chain"],
-		prometheus.GaugeValue, float64(nodeStats.SyncedToChain))
-
-	ch <- prometheus.MustNewConstMetric(c.metrics["channels_limbo_balance_satoshis"],
-		prometheus.GaugeValue, float64(pendingChannelsStats.TotalLimboBalance))
-	ch <- prometheus.MustNewConstMetric(c.metrics["channels_pending"],
-		prometheus.GaugeValue, float64(pendingChannelsStats.PendingOpenChannels), "opening", "false")
-	ch <- prometheus.MustNewConstMetric(c.metrics["channels_pending"],
-		prometheus.GaugeValue, float64(pendingChannelsStats.PendingClosingChannels), "closing", "false")
-	ch <- prometheus.MustNewConstMetric(c.metrics["channels_pending"],
-		prometheus.GaugeValue, float64(pendingChannelsStats.PendingForceClosingChannels), "closing", "true")
-	ch <- prometheus.MustNewConstMetric(c.metrics["channels_waiting_close"],
-		prometheus.GaugeValue, float64(pendingChannelsStats.WaitingCloseChannels))
-
-	ch <- prometheus.MustNewConstMetric(c.metrics["channels_balance_satoshis"],
-		prometheus.GaugeValue, float64(channelBalanceStats.TotalBalance))
+	ch <- prometheus.MustNewConstMetric(c.metrics["scrape_error"],
+		prometheus.GaugeValue, scrapeError, rpc)
+	ch <- prometheus.MustNewConstMetric(c.metrics["exporter_scrape_success"],
+		prometheus.GaugeValue, 1-scrapeError, rpc)
+	ch <- prometheus.MustNewConstMetric(c.metrics["exporter_scrape_duration_seconds"],
+		prometheus.GaugeValue, time.Since(start).Seconds(), rpc)
+	ch <- prometheus.MustNewConstMetric(c.metrics["exporter_scrape_errors_total"],
+		prometheus.CounterValue, c.scrapeErrors[rpc], rpc)
 }
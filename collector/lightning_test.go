@@ -0,0 +1,266 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// volatileMetrics are the metrics whose value depends on the time of the
+// scrape. Their value is zeroed before comparing with the golden file, which
+// still asserts their name, help, type and labels.
+var volatileMetrics = map[string]bool{
+	"lnd_exporter_scrape_duration_seconds": true,
+	"lnd_graph_last_update_timestamp":      true,
+	"lnd_seconds_since_best_header":        true,
+}
+
+func newFakeNode() *fakeLightningClient {
+	return &fakeLightningClient{
+		info: &lnrpc.GetInfoResponse{
+			IdentityPubkey:      "02aaaa",
+			Alias:               "alice",
+			NumPendingChannels:  3,
+			NumActiveChannels:   2,
+			NumInactiveChannels: 1,
+			NumPeers:            2,
+			BlockHeight:         600000,
+			SyncedToChain:       true,
+			Testnet:             false,
+			Chains:              []string{"bitcoin"},
+			Uris:                []string{"02aaaa@127.0.0.1:9735"},
+			BestHeaderTimestamp: 1570000000,
+			Version:             "0.5.2-beta commit=v0.5.2-beta",
+		},
+		walletBalance: &lnrpc.WalletBalanceResponse{
+			TotalBalance:       150000,
+			ConfirmedBalance:   100000,
+			UnconfirmedBalance: 50000,
+		},
+		channelBalance: &lnrpc.ChannelBalanceResponse{
+			Balance: 700000,
+		},
+		pendingChannels: &lnrpc.PendingChannelsResponse{
+			TotalLimboBalance: 30000,
+			PendingOpenChannels: []*lnrpc.PendingChannelsResponse_PendingOpenChannel{{
+				Channel:            &lnrpc.PendingChannelsResponse_PendingChannel{ChannelPoint: "aaaa:0", RemoteNodePub: "02bbbb"},
+				ConfirmationHeight: 599990,
+				CommitFee:          9050,
+			}},
+			PendingForceClosingChannels: []*lnrpc.PendingChannelsResponse_ForceClosedChannel{{
+				Channel:           &lnrpc.PendingChannelsResponse_PendingChannel{ChannelPoint: "bbbb:1", RemoteNodePub: "02cccc"},
+				LimboBalance:      20000,
+				MaturityHeight:    600144,
+				BlocksTilMaturity: 144,
+				RecoveredBalance:  1000,
+				PendingHtlcs:      []*lnrpc.PendingHTLC{{Amount: 500}},
+			}},
+			WaitingCloseChannels: []*lnrpc.PendingChannelsResponse_WaitingCloseChannel{{
+				Channel:      &lnrpc.PendingChannelsResponse_PendingChannel{ChannelPoint: "cccc:0", RemoteNodePub: "02dddd"},
+				LimboBalance: 10000,
+			}},
+		},
+		channels: &lnrpc.ListChannelsResponse{
+			Channels: []*lnrpc.Channel{{
+				Active:        true,
+				RemotePubkey:  "02bbbb",
+				ChannelPoint:  "dddd:0",
+				ChanId:        1,
+				Capacity:      1000000,
+				LocalBalance:  600000,
+				RemoteBalance: 390000,
+				CommitFee:     10000,
+				NumUpdates:    42,
+				PendingHtlcs:  []*lnrpc.HTLC{{Amount: 100}},
+			}, {
+				Active:        false,
+				RemotePubkey:  "02cccc",
+				ChannelPoint:  "eeee:1",
+				ChanId:        2,
+				Capacity:      200000,
+				LocalBalance:  100000,
+				RemoteBalance: 90000,
+				CommitFee:     10000,
+				Private:       true,
+			}},
+		},
+		closedChannels: &lnrpc.ClosedChannelsResponse{
+			Channels: []*lnrpc.ChannelCloseSummary{{
+				Capacity:          500000,
+				SettledBalance:    250000,
+				TimeLockedBalance: 0,
+				CloseType:         lnrpc.ChannelCloseSummary_COOPERATIVE_CLOSE,
+			}, {
+				Capacity:          300000,
+				SettledBalance:    100000,
+				TimeLockedBalance: 50000,
+				CloseType:         lnrpc.ChannelCloseSummary_LOCAL_FORCE_CLOSE,
+			}},
+		},
+		forwarding: []*lnrpc.ForwardingEvent{
+			{ChanIdIn: 1, ChanIdOut: 2, AmtIn: 10010, AmtOut: 10000, Fee: 10},
+			{ChanIdIn: 1, ChanIdOut: 2, AmtIn: 20020, AmtOut: 20000, Fee: 20},
+		},
+		feeReport: &lnrpc.FeeReportResponse{
+			ChannelFees: []*lnrpc.ChannelFeeReport{{
+				ChanPoint:   "dddd:0",
+				BaseFeeMsat: 1000,
+				FeePerMil:   1,
+				FeeRate:     0.000001,
+			}},
+			DayFeeSum:   30,
+			WeekFeeSum:  130,
+			MonthFeeSum: 530,
+		},
+		peers: &lnrpc.ListPeersResponse{
+			Peers: []*lnrpc.Peer{{
+				PubKey:    "02cccc",
+				Address:   "10.0.0.2:9735",
+				BytesSent: 2048,
+				BytesRecv: 4096,
+				SatSent:   100,
+				SatRecv:   200,
+				Inbound:   true,
+				PingTime:  250000,
+			}, {
+				PubKey:    "02bbbb",
+				Address:   "10.0.0.1:9735",
+				BytesSent: 1024,
+				BytesRecv: 512,
+				PingTime:  100000,
+			}},
+		},
+		networkInfo: &lnrpc.NetworkInfo{
+			GraphDiameter:        10,
+			AvgOutDegree:         4.5,
+			MaxOutDegree:         300,
+			NumNodes:             5000,
+			NumChannels:          30000,
+			TotalNetworkCapacity: 90000000000,
+			AvgChannelSize:       3000000,
+			MinChannelSize:       20000,
+			MaxChannelSize:       16777215,
+		},
+		invoices: []*lnrpc.Invoice{{
+			AddIndex:     1,
+			SettleIndex:  1,
+			Settled:      true,
+			Value:        1000,
+			AmtPaidSat:   1000,
+			CreationDate: 1570000000,
+			SettleDate:   1570000030,
+			Expiry:       3600,
+		}, {
+			AddIndex:     2,
+			Value:        2000,
+			CreationDate: 1570000000,
+			Expiry:       3600,
+		}},
+		payments: &lnrpc.ListPaymentsResponse{
+			Payments: []*lnrpc.Payment{{
+				ValueSat: 5000,
+				Fee:      5,
+				Path:     []string{"02bbbb", "02eeee"},
+			}, {
+				ValueSat: 10000,
+				Fee:      0,
+				Path:     []string{"02bbbb"},
+			}},
+		},
+		invoiceEvents: newFakeStream(
+			&lnrpc.Invoice{AddIndex: 3, Value: 3000},
+			&lnrpc.Invoice{AddIndex: 3, SettleIndex: 2, Settled: true, AmtPaidSat: 3000},
+		),
+		transactionEvents: newFakeStream(
+			&lnrpc.Transaction{TxHash: "ffff", Amount: 50000},
+			&lnrpc.Transaction{TxHash: "ffff", Amount: 50000, NumConfirmations: 1},
+			&lnrpc.Transaction{TxHash: "9999", Amount: -20000, TotalFees: 250},
+			&lnrpc.Transaction{TxHash: "9999", Amount: -20000, TotalFees: 250, NumConfirmations: 1},
+		),
+		graphEvents: newFakeStream(
+			&lnrpc.GraphTopologyUpdate{
+				NodeUpdates:    []*lnrpc.NodeUpdate{{}},
+				ChannelUpdates: []*lnrpc.ChannelEdgeUpdate{{}, {}},
+				ClosedChans:    []*lnrpc.ClosedChannelUpdate{{}},
+			},
+		),
+	}
+}
+
+// TestExposition asserts the exposition output of every sub-collector, so
+// metrics cannot be renamed or their help, type or labels changed without
+// updating testdata/exposition.golden. Run the tests with -update to
+// regenerate it.
+func TestExposition(t *testing.T) {
+	defer func(esploraURL string) { *chainEsploraURL = esploraURL }(*chainEsploraURL)
+
+	esplora := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "600002")
+	}))
+	defer esplora.Close()
+	*chainEsploraURL = esplora.URL
+
+	for _, r := range registrations {
+		defer func(enabled *bool, value bool) { *enabled = value }(r.enabled, *r.enabled)
+		*r.enabled = true
+	}
+
+	node := newFakeNode()
+	lightningCollector := NewLightningCollector(client.NewLightningClient(node), "lnd")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lightningCollector.Start(ctx)
+	// The invoices stream is opened by the events and invoices collectors.
+	node.invoiceEvents.waitDrained(t, 2)
+	node.transactionEvents.waitDrained(t, 1)
+	node.graphEvents.waitDrained(t, 1)
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(lightningCollector)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("gather failed: %v", err)
+	}
+	if node.infoCalls != 1 {
+		t.Errorf("expected only the state check to call GetInfo, got %d calls", node.infoCalls)
+	}
+
+	var exposition bytes.Buffer
+	for _, family := range families {
+		if volatileMetrics[family.GetName()] {
+			for _, m := range family.Metric {
+				m.Gauge.Value = new(float64)
+			}
+		}
+		if _, err := expfmt.MetricFamilyToText(&exposition, family); err != nil {
+			t.Fatalf("encoding %s failed: %v", family.GetName(), err)
+		}
+	}
+
+	golden := filepath.Join("testdata", "exposition.golden")
+	if *update {
+		if err := ioutil.WriteFile(golden, exposition.Bytes(), 0644); err != nil {
+			t.Fatalf("writing golden file failed: %v", err)
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("reading golden file failed: %v", err)
+	}
+	if !bytes.Equal(exposition.Bytes(), expected) {
+		t.Errorf("exposition does not match %s, run the tests with -update if the change is intended:\n%s", golden, exposition.String())
+	}
+}
@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	paymentPathLengthBuckets = prometheus.LinearBuckets(1, 1, 10)
+	paymentFeeRatioBuckets   = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1}
+)
+
+func init() {
+	registerCollector("payments", "list_payments", false, newPaymentsCollector)
+}
+
+// paymentsCollector reads the whole payment history on every scrape, since
+// ListPayments cannot be paged in the supported lnd version. It is therefore
+// disabled by default.
+type paymentsCollector struct {
+	lightningClient *client.LightningClient
+	metrics         map[string]*prometheus.Desc
+}
+
+func newPaymentsCollector(lightningClient *client.LightningClient, namespace string) Collector {
+	return &paymentsCollector{
+		lightningClient: lightningClient,
+		metrics: map[string]*prometheus.Desc{
+			"payments_total":               newGlobalMetric(namespace, "payments_total", "Number of payments made", []string{}),
+			"payments_satoshis_total":      newGlobalMetric(namespace, "payments_satoshis_total", "Value of the payments made", []string{}),
+			"payments_fees_satoshis_total": newGlobalMetric(namespace, "payments_fees_satoshis_total", "Routing fees paid for the payments made", []string{}),
+			"payment_path_length":          newGlobalMetric(namespace, "payment_path_length", "Number of hops of the payments made", []string{}),
+			"payment_fee_ratio":            newGlobalMetric(namespace, "payment_fee_ratio", "Routing fees paid relative to the value of the payments made", []string{}),
+		},
+	}
+}
+
+func (c *paymentsCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeMetrics(ch, c.metrics)
+}
+
+func (c *paymentsCollector) Update(ch chan<- prometheus.Metric) error {
+	paymentsStats, err := c.lightningClient.GetPaymentsStats()
+	if err != nil {
+		return err
+	}
+
+	var value, fees int64
+	pathLengths := make([]float64, 0, len(paymentsStats))
+	feeRatios := make([]float64, 0, len(paymentsStats))
+	for _, payment := range paymentsStats {
+		value += payment.ValueSat
+		fees += payment.Fee
+		pathLengths = append(pathLengths, float64(payment.PathLength))
+		if payment.ValueSat > 0 {
+			feeRatios = append(feeRatios, float64(payment.Fee)/float64(payment.ValueSat))
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.metrics["payments_total"],
+		prometheus.CounterValue, float64(len(paymentsStats)))
+	ch <- prometheus.MustNewConstMetric(c.metrics["payments_satoshis_total"],
+		prometheus.CounterValue, float64(value))
+	ch <- prometheus.MustNewConstMetric(c.metrics["payments_fees_satoshis_total"],
+		prometheus.CounterValue, float64(fees))
+	ch <- newConstHistogram(c.metrics["payment_path_length"], paymentPathLengthBuckets, pathLengths)
+	ch <- newConstHistogram(c.metrics["payment_fee_ratio"], paymentFeeRatioBuckets, feeRatios)
+
+	return nil
+}
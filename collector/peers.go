@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"flag"
+	"sort"
+	"strings"
+
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	peerLabels = []string{"pub_key", "address"}
+
+	peersAllowlist = flag.String("collector.peers.allowlist", "",
+		"Comma separated list of peer public keys to export metrics for. All peers are exported when empty.")
+	peersLimit = flag.Int("collector.peers.limit", 0,
+		"Maximum number of peers to export metrics for, in public key order. There is no limit when 0.")
+)
+
+func init() {
+	registerCollector("peers", "list_peers", true, newPeersCollector)
+}
+
+type peersCollector struct {
+	lightningClient *client.LightningClient
+	metrics         map[string]*prometheus.Desc
+	allowlist       map[string]bool
+	limit           int
+}
+
+func newPeersCollector(lightningClient *client.LightningClient, namespace string) Collector {
+	allowlist := map[string]bool{}
+	for _, pubKey := range strings.Split(*peersAllowlist, ",") {
+		if pubKey = strings.TrimSpace(pubKey); pubKey != "" {
+			allowlist[pubKey] = true
+		}
+	}
+
+	return &peersCollector{
+		lightningClient: lightningClient,
+		allowlist:       allowlist,
+		limit:           *peersLimit,
+		metrics: map[string]*prometheus.Desc{
+			"peer_sent_bytes":        newGlobalMetric(namespace, "peer_sent_bytes", "Bytes sent to the peer", peerLabels),
+			"peer_received_bytes":    newGlobalMetric(namespace, "peer_received_bytes", "Bytes received from the peer", peerLabels),
+			"peer_sent_satoshis":     newGlobalMetric(namespace, "peer_sent_satoshis", "Satoshis sent to the peer", peerLabels),
+			"peer_received_satoshis": newGlobalMetric(namespace, "peer_received_satoshis", "Satoshis received from the peer", peerLabels),
+			"peer_ping_time_seconds": newGlobalMetric(namespace, "peer_ping_time_seconds", "Ping time to the peer", peerLabels),
+			"peer_inbound":           newGlobalMetric(namespace, "peer_inbound", "Whether the connection was initiated by the peer", peerLabels),
+		},
+	}
+}
+
+func (c *peersCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeMetrics(ch, c.metrics)
+}
+
+func (c *peersCollector) Update(ch chan<- prometheus.Metric) error {
+	peersStats, err := c.lightningClient.GetPeersStats()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(peersStats, func(i, j int) bool {
+		return peersStats[i].PubKey < peersStats[j].PubKey
+	})
+
+	exported := 0
+	for _, peer := range peersStats {
+		if len(c.allowlist) > 0 && !c.allowlist[peer.PubKey] {
+			continue
+		}
+		if c.limit > 0 && exported >= c.limit {
+			break
+		}
+		exported++
+
+		labels := []string{peer.PubKey, peer.Address}
+		ch <- prometheus.MustNewConstMetric(c.metrics["peer_sent_bytes"],
+			prometheus.GaugeValue, float64(peer.BytesSent), labels...)
+		ch <- prometheus.MustNewConstMetric(c.metrics["peer_received_bytes"],
+			prometheus.GaugeValue, float64(peer.BytesRecv), labels...)
+		ch <- prometheus.MustNewConstMetric(c.metrics["peer_sent_satoshis"],
+			prometheus.GaugeValue, float64(peer.SatSent), labels...)
+		ch <- prometheus.MustNewConstMetric(c.metrics["peer_received_satoshis"],
+			prometheus.GaugeValue, float64(peer.SatRecv), labels...)
+		ch <- prometheus.MustNewConstMetric(c.metrics["peer_ping_time_seconds"],
+			prometheus.GaugeValue, float64(peer.PingTime)/1e6, labels...)
+		ch <- prometheus.MustNewConstMetric(c.metrics["peer_inbound"],
+			prometheus.GaugeValue, float64(boolToInt(peer.Inbound)), labels...)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var pendingChannelLabels = []string{"channel_point", "remote_node_pub"}
+
+func init() {
+	registerCollector("pending", "pending_channels", true, newPendingCollector)
+}
+
+type pendingCollector struct {
+	lightningClient *client.LightningClient
+	metrics         map[string]*prometheus.Desc
+}
+
+func newPendingCollector(lightningClient *client.LightningClient, namespace string) Collector {
+	return &pendingCollector{
+		lightningClient: lightningClient,
+		metrics: map[string]*prometheus.Desc{
+			"channel_limbo_balance_satoshis":                  newGlobalMetric(namespace, "channel_limbo_balance_satoshis", "The balance in satoshis encumbered in pending channels", []string{}),
+			"channel_pending":                                 newGlobalMetric(namespace, "channel_pending", "The total pending channels", []string{"status", "forced"}),
+			"channel_waiting_close":                           newGlobalMetric(namespace, "channel_waiting_close", "Channels waiting for closing tx to confirm", []string{}),
+			"pending_open_channel_confirmation_height":        newGlobalMetric(namespace, "pending_open_channel_confirmation_height", "The height at which the funding transaction was first confirmed", pendingChannelLabels),
+			"pending_open_channel_commit_fee_satoshis":        newGlobalMetric(namespace, "pending_open_channel_commit_fee_satoshis", "The fee the channel initiator pays for the commitment transaction", pendingChannelLabels),
+			"force_closed_channel_limbo_balance_satoshis":     newGlobalMetric(namespace, "force_closed_channel_limbo_balance_satoshis", "The balance in satoshis encumbered in the force closed channel", pendingChannelLabels),
+			"force_closed_channel_maturity_height":            newGlobalMetric(namespace, "force_closed_channel_maturity_height", "The height at which the funds of the force closed channel can be swept", pendingChannelLabels),
+			"force_closed_channel_blocks_til_maturity":        newGlobalMetric(namespace, "force_closed_channel_blocks_til_maturity", "The number of blocks until the funds of the force closed channel can be swept", pendingChannelLabels),
+			"force_closed_channel_recovered_balance_satoshis": newGlobalMetric(namespace, "force_closed_channel_recovered_balance_satoshis", "The balance already recovered from the force closed channel", pendingChannelLabels),
+			"force_closed_channel_pending_htlcs":              newGlobalMetric(namespace, "force_closed_channel_pending_htlcs", "The number of HTLCs pending in the force closed channel", pendingChannelLabels),
+			"waiting_close_channel_limbo_balance_satoshis":    newGlobalMetric(namespace, "waiting_close_channel_limbo_balance_satoshis", "The balance in satoshis encumbered in the channel waiting for its closing tx to confirm", pendingChannelLabels),
+		},
+	}
+}
+
+func (c *pendingCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeMetrics(ch, c.metrics)
+}
+
+func (c *pendingCollector) Update(ch chan<- prometheus.Metric) error {
+	pendingChannelsStats, err := c.lightningClient.GetPendingChannelsStats()
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.metrics["channel_limbo_balance_satoshis"],
+		prometheus.GaugeValue, float64(pendingChannelsStats.TotalLimboBalance))
+	ch <- prometheus.MustNewConstMetric(c.metrics["channel_pending"],
+		prometheus.GaugeValue, float64(pendingChannelsStats.PendingOpenChannels), "opening", "false")
+	ch <- prometheus.MustNewConstMetric(c.metrics["channel_pending"],
+		prometheus.GaugeValue, float64(pendingChannelsStats.PendingClosingChannels), "closing", "false")
+	ch <- prometheus.MustNewConstMetric(c.metrics["channel_pending"],
+		prometheus.GaugeValue, float64(pendingChannelsStats.PendingForceClosingChannels), "closing", "true")
+	ch <- prometheus.MustNewConstMetric(c.metrics["channel_waiting_close"],
+		prometheus.GaugeValue, float64(pendingChannelsStats.WaitingCloseChannels))
+
+	for _, channel := range pendingChannelsStats.PendingOpen {
+		ch <- prometheus.MustNewConstMetric(c.metrics["pending_open_channel_confirmation_height"],
+			prometheus.GaugeValue, float64(channel.ConfirmationHeight), channel.ChannelPoint, channel.RemoteNodePub)
+		ch <- prometheus.MustNewConstMetric(c.metrics["pending_open_channel_commit_fee_satoshis"],
+			prometheus.GaugeValue, float64(channel.CommitFee), channel.ChannelPoint, channel.RemoteNodePub)
+	}
+
+	for _, channel := range pendingChannelsStats.PendingForceClosing {
+		ch <- prometheus.MustNewConstMetric(c.metrics["force_closed_channel_limbo_balance_satoshis"],
+			prometheus.GaugeValue, float64(channel.LimboBalance), channel.ChannelPoint, channel.RemoteNodePub)
+		ch <- prometheus.MustNewConstMetric(c.metrics["force_closed_channel_maturity_height"],
+			prometheus.GaugeValue, float64(channel.MaturityHeight), channel.ChannelPoint, channel.RemoteNodePub)
+		ch <- prometheus.MustNewConstMetric(c.metrics["force_closed_channel_blocks_til_maturity"],
+			prometheus.GaugeValue, float64(channel.BlocksTilMaturity), channel.ChannelPoint, channel.RemoteNodePub)
+		ch <- prometheus.MustNewConstMetric(c.metrics["force_closed_channel_recovered_balance_satoshis"],
+			prometheus.GaugeValue, float64(channel.RecoveredBalance), channel.ChannelPoint, channel.RemoteNodePub)
+		ch <- prometheus.MustNewConstMetric(c.metrics["force_closed_channel_pending_htlcs"],
+			prometheus.GaugeValue, float64(channel.PendingHtlcs), channel.ChannelPoint, channel.RemoteNodePub)
+	}
+
+	for _, channel := range pendingChannelsStats.WaitingClose {
+		ch <- prometheus.MustNewConstMetric(c.metrics["waiting_close_channel_limbo_balance_satoshis"],
+			prometheus.GaugeValue, float64(channel.LimboBalance), channel.ChannelPoint, channel.RemoteNodePub)
+	}
+
+	return nil
+}
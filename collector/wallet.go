@@ -0,0 +1,42 @@
+package collector
+
+import (
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("wallet", "wallet_balance", true, newWalletCollector)
+}
+
+type walletCollector struct {
+	lightningClient *client.LightningClient
+	metrics         map[string]*prometheus.Desc
+}
+
+func newWalletCollector(lightningClient *client.LightningClient, namespace string) Collector {
+	return &walletCollector{
+		lightningClient: lightningClient,
+		metrics: map[string]*prometheus.Desc{
+			"wallet_balance_satoshis": newGlobalMetric(namespace, "wallet_balance_satoshis", "The wallet balance.", []string{"status"}),
+		},
+	}
+}
+
+func (c *walletCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeMetrics(ch, c.metrics)
+}
+
+func (c *walletCollector) Update(ch chan<- prometheus.Metric) error {
+	walletStats, err := c.lightningClient.GetWalletStats()
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.metrics["wallet_balance_satoshis"],
+		prometheus.GaugeValue, float64(walletStats.UnconfirmedBalance), "unconfirmed")
+	ch <- prometheus.MustNewConstMetric(c.metrics["wallet_balance_satoshis"],
+		prometheus.GaugeValue, float64(walletStats.ConfirmedBalance), "confirmed")
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config holds the settings read from the configuration file. Every setting
+// has a matching flag, which takes precedence over it when given on the
+// command line.
+type Config struct {
+	Namespace      string `yaml:"namespace"`
+	ListenAddress  string `yaml:"listen_address"`
+	MetricsPath    string `yaml:"telemetry_path"`
+	RPCHost        string `yaml:"rpc_host"`
+	RPCPort        string `yaml:"rpc_port"`
+	TLSCertPath    string `yaml:"tls_cert_path"`
+	MacaroonPath   string `yaml:"macaroon_path"`
+	MacaroonHex    string `yaml:"macaroon_hex"`
+	MacaroonBase64 string `yaml:"macaroon_base64"`
+	TLSCert        string `yaml:"tls_cert"`
+	NoMacaroons    *bool  `yaml:"no_macaroons"`
+	TLSSkipVerify  *bool  `yaml:"tls_skip_verify"`
+	TLSFingerprint string `yaml:"tls_cert_fingerprint"`
+	LndDir         string `yaml:"lnd_dir"`
+	LndNetwork     string `yaml:"lnd_network"`
+	GoMetrics      *bool  `yaml:"go_metrics"`
+	PollInterval   string `yaml:"poll_interval"`
+	ReloadInterval string `yaml:"reload_interval"`
+	RPCTimeout     string `yaml:"rpc_timeout"`
+	Nodes          []Node `yaml:"nodes"`
+
+	// Collectors holds the options of every collector by collector name.
+	// The enabled option sets the --collector.<name> flag and any other
+	// option the --collector.<name>.<option> flag. List values are joined
+	// with commas.
+	Collectors map[string]map[string]interface{} `yaml:"collectors"`
+}
+
+// loadConfig reads the configuration file at path and applies its settings to
+// the flags not given on the command line.
+func loadConfig(path string) (*Config, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, err
+	}
+	if len(config.Nodes) > 0 {
+		if err := validateNodes(config.Nodes); err != nil {
+			return nil, err
+		}
+	}
+
+	settings := map[string]string{
+		"namespace":                config.Namespace,
+		"web.listen-address":       config.ListenAddress,
+		"web.telemetry-path":       config.MetricsPath,
+		"rpc.host":                 config.RPCHost,
+		"rpc.port":                 config.RPCPort,
+		"lnd.tls-cert-path":        config.TLSCertPath,
+		"lnd.macaroon-path":        config.MacaroonPath,
+		"lnd.macaroon-hex":         config.MacaroonHex,
+		"lnd.macaroon-base64":      config.MacaroonBase64,
+		"lnd.tls-cert":             config.TLSCert,
+		"lnd.no-macaroons":         formatConfigBool(config.NoMacaroons),
+		"lnd.tls-skip-verify":      formatConfigBool(config.TLSSkipVerify),
+		"lnd.tls-cert-fingerprint": config.TLSFingerprint,
+		"lnd.dir":                  config.LndDir,
+		"lnd.network":              config.LndNetwork,
+		"go-metrics":               formatConfigBool(config.GoMetrics),
+		"poll.interval":            config.PollInterval,
+		"lnd.reload-interval":      config.ReloadInterval,
+		"lnd.rpc-timeout":          config.RPCTimeout,
+	}
+	for name, options := range config.Collectors {
+		for option, value := range options {
+			flagName := "collector." + name
+			if option != "enabled" {
+				flagName += "." + option
+			}
+			settings[flagName] = formatConfigValue(value)
+		}
+	}
+
+	explicit := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		explicit[strings.TrimPrefix(f.Name, "no-")] = true
+	})
+
+	for name, value := range settings {
+		if value == "" || explicit[name] {
+			continue
+		}
+		if flag.Lookup(name) == nil {
+			return nil, fmt.Errorf("unknown setting %s", name)
+		}
+		if err := flag.Set(name, value); err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s: %v", value, name, err)
+		}
+	}
+
+	return &config, nil
+}
+
+// formatConfigBool formats a boolean setting as a flag value, or as an empty
+// value when it was not set.
+func formatConfigBool(value *bool) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatBool(*value)
+}
+
+func formatConfigValue(value interface{}) string {
+	values, ok := value.([]interface{})
+	if !ok {
+		return fmt.Sprint(value)
+	}
+
+	items := make([]string, 0, len(values))
+	for _, v := range values {
+		items = append(items, fmt.Sprint(v))
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigInvalidBool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatalf("creating config directory failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yml")
+	if err := ioutil.WriteFile(path, []byte("namespace: lnd\nno_macaroons: maybe\n"), 0600); err != nil {
+		t.Fatalf("writing config file failed: %v", err)
+	}
+
+	_, err = loadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error reporting line 2, got %v", err)
+	}
+}
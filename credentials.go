@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+	macaroon "gopkg.in/macaroon.v2"
+)
+
+// validateConnectionMode checks that the connection settings of the node do
+// not contradict each other.
+func validateConnectionMode(node Node) error {
+	if strings.HasPrefix(node.Host, "unix://") && strings.TrimPrefix(node.Host, "unix://") == "" {
+		return fmt.Errorf("unix socket host must include the socket path, e.g. unix:///var/run/lnd.sock")
+	}
+
+	if node.NoMacaroons && (node.MacaroonPath != "" || node.MacaroonHex != "" || node.MacaroonBase64 != "") {
+		return fmt.Errorf("a macaroon cannot be given when connecting without macaroons")
+	}
+	if !node.NoMacaroons && node.MacaroonPath == "" && node.MacaroonHex == "" && node.MacaroonBase64 == "" {
+		return fmt.Errorf("no macaroon given, set a macaroon path, hex or base64 macaroon or connect without macaroons")
+	}
+
+	if node.TLSSkipVerify && node.TLSCert != "" {
+		return fmt.Errorf("a tls certificate cannot be given when skipping its verification, use a fingerprint instead")
+	}
+	if node.TLSCertFingerprint != "" {
+		if !node.TLSSkipVerify {
+			return fmt.Errorf("a tls certificate fingerprint can only be given when skipping its verification")
+		}
+		if _, err := decodeFingerprint(node.TLSCertFingerprint); err != nil {
+			return err
+		}
+	}
+
+	// Inline credentials never change, so they are decoded now rather than
+	// retried like the files, which may not be readable yet.
+	if node.MacaroonHex != "" || node.MacaroonBase64 != "" {
+		macBytes, err := loadMacaroon(node)
+		if err != nil {
+			return err
+		}
+		if err := (&macaroon.Macaroon{}).UnmarshalBinary(macBytes); err != nil {
+			return fmt.Errorf("unable to decode macaroon: %v", err)
+		}
+	}
+	if node.TLSCert != "" {
+		if _, err := loadTLSCredentials(node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeFingerprint decodes a hex SHA-256 fingerprint, optionally separated by
+// colons.
+func decodeFingerprint(fingerprint string) ([]byte, error) {
+	decoded, err := hex.DecodeString(strings.Replace(strings.TrimSpace(fingerprint), ":", "", -1))
+	if err != nil || len(decoded) != sha256.Size {
+		return nil, fmt.Errorf("tls certificate fingerprint must be a hex encoded SHA-256 hash")
+	}
+	return decoded, nil
+}
+
+// loadTLSCredentials builds the transport credentials from the inline PEM
+// certificate of the node or, when not given, from its certificate file. When
+// skipping the verification, the certificate is only checked against the
+// fingerprint of the node, if any.
+func loadTLSCredentials(node Node) (credentials.TransportCredentials, error) {
+	// gRPC would verify the certificate of a unix socket against the
+	// "unix" server name, so localhost, which lnd always includes in its
+	// certificate, is verified instead.
+	serverName := ""
+	if strings.HasPrefix(node.Host, "unix://") {
+		serverName = "localhost"
+	}
+
+	if node.TLSSkipVerify {
+		config := &tls.Config{InsecureSkipVerify: true}
+		if node.TLSCertFingerprint != "" {
+			fingerprint, err := decodeFingerprint(node.TLSCertFingerprint)
+			if err != nil {
+				return nil, err
+			}
+			config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+				if len(rawCerts) == 0 {
+					return fmt.Errorf("no tls certificate presented")
+				}
+				sum := sha256.Sum256(rawCerts[0])
+				if !bytes.Equal(sum[:], fingerprint) {
+					return fmt.Errorf("tls certificate fingerprint %x does not match", sum)
+				}
+				return nil
+			}
+		}
+		return credentials.NewTLS(config), nil
+	}
+
+	if node.TLSCert == "" {
+		creds, err := credentials.NewClientTLSFromFile(node.TLSCertPath, serverName)
+		if err != nil {
+			return nil, fmt.Errorf("could not find TLS certificate: %v", err)
+		}
+		return creds, nil
+	}
+
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM([]byte(node.TLSCert)) {
+		return nil, fmt.Errorf("unable to decode TLS certificate: no PEM certificate found")
+	}
+
+	return credentials.NewClientTLSFromCert(certPool, serverName), nil
+}
+
+// loadMacaroon returns the macaroon bytes from the inline hex or base64
+// macaroon of the node or, when neither is given, from its macaroon file.
+func loadMacaroon(node Node) ([]byte, error) {
+	switch {
+	case node.MacaroonHex != "" && node.MacaroonBase64 != "":
+		return nil, fmt.Errorf("only one of the hex and base64 macaroons can be given")
+
+	case node.MacaroonHex != "":
+		macBytes, err := hex.DecodeString(strings.TrimSpace(node.MacaroonHex))
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode hex macaroon: %v", err)
+		}
+		return macBytes, nil
+
+	case node.MacaroonBase64 != "":
+		macBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(node.MacaroonBase64))
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode base64 macaroon: %v", err)
+		}
+		return macBytes, nil
+	}
+
+	macBytes, err := ioutil.ReadFile(node.MacaroonPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not find Macaroon: %v", err)
+	}
+	return macBytes, nil
+}
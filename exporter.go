@@ -1,22 +1,22 @@
 package main
 
 import (
+	"context"
 	"flag"
-	"io/ioutil"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/lightningnetwork/lnd/lncfg"
-	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/macaroons"
 	"github.com/platanus/lightning-prometheus-exporter/client"
 	"github.com/platanus/lightning-prometheus-exporter/collector"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 	macaroon "gopkg.in/macaroon.v2"
 )
 
@@ -40,6 +40,18 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return b
 }
 
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Environment Variable value for %s must be a duration", key)
+	}
+	return d
+}
+
 var (
 	// Set during go build
 	version   string
@@ -50,14 +62,27 @@ var (
 	maxMsgRecvSize = grpc.MaxCallRecvMsgSize(1 * 1024 * 1024 * 50)
 
 	// Defaults values
-	defaultNamespace     = getEnv("NAMESPACE", "lnd")
-	defaultListenAddress = getEnv("LISTEN_ADDRESS", ":9113")
-	defaultMetricsPath   = getEnv("TELEMETRY_PATH", "/metrics")
-	defaultRPCHost       = getEnv("RPC_HOST", "localhost")
-	defaultRPCPort       = getEnv("RPC_PORT", "10009")
-	defaultTLSCertPath   = getEnv("TLS_CERT_PATH", "/root/.lnd")
-	defaultMacaroonPath  = getEnv("MACAROON_PATH", "")
-	defaultGoMetrics, _  = strconv.ParseBool(getEnv("GO_METRICS", "false"))
+	defaultNamespace      = getEnv("NAMESPACE", "lnd")
+	defaultListenAddress  = getEnv("LISTEN_ADDRESS", ":9113")
+	defaultMetricsPath    = getEnv("TELEMETRY_PATH", "/metrics")
+	defaultRPCHost        = getEnv("RPC_HOST", "localhost")
+	defaultRPCPort        = getEnv("RPC_PORT", "10009")
+	defaultTLSCertPath    = getEnv("TLS_CERT_PATH", "/root/.lnd/tls.cert")
+	defaultMacaroonPath   = getEnv("MACAROON_PATH", "")
+	defaultMacaroonHex    = getEnv("MACAROON_HEX", "")
+	defaultMacaroonBase64 = getEnv("MACAROON_BASE64", "")
+	defaultTLSCert        = getEnv("TLS_CERT", "")
+	defaultNoMacaroons    = getEnvBool("NO_MACAROONS", false)
+	defaultTLSSkipVerify  = getEnvBool("TLS_SKIP_VERIFY", false)
+	defaultTLSFingerprint = getEnv("TLS_CERT_FINGERPRINT", "")
+	defaultGoMetrics, _   = strconv.ParseBool(getEnv("GO_METRICS", "false"))
+	defaultPollInterval   = getEnvDuration("POLL_INTERVAL", 0)
+	defaultNodesFile      = getEnv("NODES_FILE", "")
+	defaultConfigFile     = getEnv("CONFIG_FILE", "")
+	defaultReloadInterval = getEnvDuration("RELOAD_INTERVAL", 0)
+	defaultRPCTimeout     = getEnvDuration("RPC_TIMEOUT", client.DefaultTimeout)
+	defaultLndDir         = getEnv("LND_DIR", "")
+	defaultLndNetwork     = getEnv("LND_NETWORK", "mainnet")
 
 	// Command-line flags
 	namespace = flag.String("namespace", defaultNamespace,
@@ -74,26 +99,125 @@ var (
 		"The path to the tls certificate. The default value can be overwritten by TLS_CERT_PATH environment variable.")
 	macaroonPath = flag.String("lnd.macaroon-path", defaultMacaroonPath,
 		"The path to the read only macaroon. The default value can be overwritten by MACAROON_PATH environment variable.")
+	macaroonHex = flag.String("lnd.macaroon-hex", defaultMacaroonHex,
+		"The read only macaroon encoded in hex, used instead of the macaroon path. The default value can be overwritten by MACAROON_HEX environment variable.")
+	macaroonBase64 = flag.String("lnd.macaroon-base64", defaultMacaroonBase64,
+		"The read only macaroon encoded in base64, used instead of the macaroon path. The default value can be overwritten by MACAROON_BASE64 environment variable.")
+	tlsCert = flag.String("lnd.tls-cert", defaultTLSCert,
+		"The PEM encoded tls certificate, used instead of the tls certificate path. The default value can be overwritten by TLS_CERT environment variable.")
+	noMacaroons = flag.Bool("lnd.no-macaroons", defaultNoMacaroons,
+		"Connect without a macaroon, to nodes running with --no-macaroons. The default value can be overwritten by NO_MACAROONS environment variable.")
+	tlsSkipVerify = flag.Bool("lnd.tls-skip-verify", defaultTLSSkipVerify,
+		"Skip the verification of the tls certificate, which is then not loaded. The default value can be overwritten by TLS_SKIP_VERIFY environment variable.")
+	tlsFingerprint = flag.String("lnd.tls-cert-fingerprint", defaultTLSFingerprint,
+		"The hex encoded SHA-256 fingerprint the tls certificate must match when skipping its verification. The default value can be overwritten by TLS_CERT_FINGERPRINT environment variable.")
+	lndDir = flag.String("lnd.dir", defaultLndDir,
+		"The lnd directory to discover the tls certificate, read only macaroon and rpc address from, including its lnd.conf. The rpc and lnd flags given explicitly take precedence. The default value can be overwritten by LND_DIR environment variable.")
+	lndNetwork = flag.String("lnd.network", defaultLndNetwork,
+		"The bitcoin network of the node, used to find the macaroon in the lnd directory when lnd.conf does not set it. The default value can be overwritten by LND_NETWORK environment variable.")
 	goMetrics = flag.Bool("go-metrics", defaultGoMetrics,
 		"Enable process and go metrics from go client library. The default value can be overwritten by GO_METRICS environmental variable.")
+	reloadInterval = flag.Duration("lnd.reload-interval", defaultReloadInterval,
+		"How often to check the tls certificate and macaroon files for changes, reconnecting to the node when they change. They are only reloaded on SIGHUP when 0. The default value can be overwritten by RELOAD_INTERVAL environment variable.")
+	rpcTimeout = flag.Duration("lnd.rpc-timeout", defaultRPCTimeout,
+		"The deadline of every rpc call to the node, so a node that stops answering cannot block the scrapes. It must be shorter than the poll interval. The default value can be overwritten by RPC_TIMEOUT environment variable.")
+	pollInterval = flag.Duration("poll.interval", defaultPollInterval,
+		"How often to refresh the metrics in the background, served from the last refresh on scrapes. Metrics are fetched on every scrape when 0. The default value can be overwritten by POLL_INTERVAL environment variable.")
+	configFile = flag.String("config.file", defaultConfigFile,
+		"The path to a YAML configuration file. Flags given on the command line take precedence over its settings. The default value can be overwritten by CONFIG_FILE environment variable.")
+	nodesFile = flag.String("nodes.file", defaultNodesFile,
+		"The path to a YAML file listing the nodes to monitor, used instead of the rpc and lnd flags. The default value can be overwritten by NODES_FILE environment variable.")
 )
 
 func main() {
 	flag.Parse()
 
-	log.Printf("Starting Lightning Prometheus Exporter Version=%v GitCommit=%v", version, gitCommit)
-
-	var connCfg = getClientConn()
-	rpcclient := lnrpc.NewLightningClient(connCfg)
+	var nodes []Node
+	if *configFile != "" {
+		config, err := loadConfig(*configFile)
+		if err != nil {
+			log.Fatalf("Could not load config file: %v", err)
+		}
+		nodes = config.Nodes
+	}
+	if *nodesFile != "" {
+		var err error
+		nodes, err = loadNodes(*nodesFile)
+		if err != nil {
+			log.Fatalf("Could not load nodes file: %v", err)
+		}
+	}
 
-	client, err := client.NewLightningClient(rpcclient)
-	if err != nil {
-		log.Fatalf("Could not create Lightning Rpc Client: %v", err)
+	if *rpcTimeout <= 0 || (*pollInterval > 0 && *rpcTimeout >= *pollInterval) {
+		log.Fatalf("Invalid rpc timeout %v: it must be positive and shorter than the poll interval", *rpcTimeout)
 	}
+	if err := collector.ValidateCollectors(); err != nil {
+		log.Fatalf("Invalid collector settings: %v", err)
+	}
+
+	log.Printf("Starting Lightning Prometheus Exporter Version=%v GitCommit=%v", version, gitCommit)
+	log.Printf("Enabled collectors: %v", collector.EnabledCollectors())
 
 	// registry
 	registry := prometheus.NewRegistry()
-	registry.MustRegister(collector.NewLightningCollector(client, *namespace))
+
+	connections := []*nodeConnection{}
+	if len(nodes) == 0 {
+		node := Node{
+			Host:           *rpcHost,
+			Port:           *rpcPort,
+			TLSCertPath:    *tlsCertPath,
+			MacaroonPath:   *macaroonPath,
+			MacaroonHex:    *macaroonHex,
+			MacaroonBase64: *macaroonBase64,
+			TLSCert:        *tlsCert,
+
+			NoMacaroons:        *noMacaroons,
+			TLSSkipVerify:      *tlsSkipVerify,
+			TLSCertFingerprint: *tlsFingerprint,
+		}
+		if *lndDir != "" {
+			explicit := explicitSettings(map[string]string{
+				"rpc.host":          "RPC_HOST",
+				"rpc.port":          "RPC_PORT",
+				"lnd.tls-cert-path": "TLS_CERT_PATH",
+				"lnd.macaroon-path": "MACAROON_PATH",
+				"lnd.network":       "LND_NETWORK",
+			})
+			if err := applyLndDir(&node, expandPath(*lndDir), *lndNetwork, explicit); err != nil {
+				log.Fatalf("Could not read lnd directory: %v", err)
+			}
+		}
+
+		connection, err := connectNode(node)
+		if err != nil {
+			log.Fatalf("Could not create Lightning Rpc Client: %v", err)
+		}
+		connections = append(connections, connection)
+		registry.MustRegister(newNodeCollector(connection.client))
+	} else {
+		targets := map[string]*collector.LightningCollector{}
+		for _, node := range nodes {
+			var lightningCollector *collector.LightningCollector
+			connection, err := connectNode(node)
+			if err != nil {
+				log.Printf("Could not create Lightning Rpc Client for node %s: %v", node.Name, err)
+				lightningCollector = newUnavailableCollector()
+			} else {
+				connections = append(connections, connection)
+				lightningCollector = newNodeCollector(connection.client)
+			}
+			targets[node.Name] = lightningCollector
+			prometheus.WrapRegistererWith(prometheus.Labels{"node": node.Name}, registry).MustRegister(lightningCollector)
+		}
+
+		http.HandleFunc("/probe", probeHandler(targets))
+	}
+
+	go reloadOnSignal(connections)
+	if *reloadInterval > 0 {
+		go watchCredentials(connections, *reloadInterval)
+	}
 
 	if *goMetrics {
 		registry.MustRegister(prometheus.NewGoCollector())
@@ -113,12 +237,57 @@ func main() {
 	log.Fatal(http.ListenAndServe(*listenAddr, nil))
 }
 
-func getClientConn() *grpc.ClientConn {
+// newNodeCollector creates the collector of the node metrics and starts its
+// background work, which runs as long as the exporter.
+func newNodeCollector(lightningClient *client.LightningClient) *collector.LightningCollector {
+	lightningCollector := collector.NewLightningCollector(lightningClient, *namespace)
+	lightningCollector.Start(context.Background())
+	if *pollInterval > 0 {
+		lightningCollector.StartPolling(*pollInterval)
+	}
+
+	return lightningCollector
+}
+
+// newUnavailableCollector creates the collector of a node that cannot be
+// monitored because of its settings. Its client is never connected, so the
+// node is reported as down and disconnected instead of missing.
+func newUnavailableCollector() *collector.LightningCollector {
+	return collector.NewLightningCollector(client.NewLightningClient(nil), *namespace)
+}
+
+// probeHandler serves the metrics of the node given by the target parameter.
+func probeHandler(targets map[string]*collector.LightningCollector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			http.Error(w, "'target' parameter must be specified", http.StatusBadRequest)
+			return
+		}
+		lightningCollector, ok := targets[target]
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unknown target %q", target), http.StatusNotFound)
+			return
+		}
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(lightningCollector)
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}
+}
+
+// getClientConn connects to the node. The extra dial options are applied after
+// the default ones, so they can replace them, e.g. the dialer in tests.
+func getClientConn(node Node, extraOpts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if err := validateConnectionMode(node); err != nil {
+		return nil, err
+	}
+
 	// Load the specified TLS certificate and build transport credentials
 	// with it.
-	creds, err := credentials.NewClientTLSFromFile(*tlsCertPath, "")
+	creds, err := loadTLSCredentials(node)
 	if err != nil {
-		log.Fatalf("could not find TLS certificate: %v", err)
+		return nil, err
 	}
 
 	// Create a dial options array.
@@ -126,31 +295,34 @@ func getClientConn() *grpc.ClientConn {
 		grpc.WithTransportCredentials(creds),
 	}
 
-	// Load the specified macaroon file.
-	macBytes, err := ioutil.ReadFile(*macaroonPath)
-	if err != nil {
-		log.Fatalf("could not find Macaroon: %v", err)
-	}
+	// Load the specified macaroon, unless the node runs without them.
+	if !node.NoMacaroons {
+		macBytes, err := loadMacaroon(node)
+		if err != nil {
+			return nil, err
+		}
 
-	mac := &macaroon.Macaroon{}
-	if err = mac.UnmarshalBinary(macBytes); err != nil {
-		log.Fatalf("unable to decode macaroon: %v", err)
-	}
+		mac := &macaroon.Macaroon{}
+		if err = mac.UnmarshalBinary(macBytes); err != nil {
+			return nil, fmt.Errorf("unable to decode macaroon: %v", err)
+		}
 
-	// Now we append the macaroon credentials to the dial options.
-	cred := macaroons.NewMacaroonCredential(mac)
-	opts = append(opts, grpc.WithPerRPCCredentials(cred))
+		// Now we append the macaroon credentials to the dial options.
+		cred := macaroons.NewMacaroonCredential(mac)
+		opts = append(opts, grpc.WithPerRPCCredentials(cred))
+	}
 
 	// We need to use a custom dialer so we can also connect to unix sockets
 	// and not just TCP addresses.
-	genericDialer := lncfg.ClientAddressDialer(*rpcPort)
+	genericDialer := lncfg.ClientAddressDialer(node.Port)
 	opts = append(opts, grpc.WithDialer(genericDialer))
 	opts = append(opts, grpc.WithDefaultCallOptions(maxMsgRecvSize))
+	opts = append(opts, extraOpts...)
 
-	conn, err := grpc.Dial(*rpcHost, opts...)
+	conn, err := grpc.Dial(node.Host, opts...)
 	if err != nil {
-		log.Fatalf("unable to connect to RPC server: %v", err)
+		return nil, fmt.Errorf("unable to connect to RPC server: %v", err)
 	}
 
-	return conn
+	return conn, nil
 }
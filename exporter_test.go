@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"github.com/platanus/lightning-prometheus-exporter/internal/lndtest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func startNode(t *testing.T, options lndtest.Options) *lndtest.Node {
+	node, err := lndtest.StartNode(options)
+	if err != nil {
+		t.Fatalf("starting fake lnd failed: %v", err)
+	}
+	return node
+}
+
+// getInfo connects to the fake lnd with getClientConn and calls GetInfo.
+func getInfo(fake *lndtest.Node, node Node) error {
+	conn, err := getClientConn(node, grpc.WithDialer(fake.Dialer()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = lnrpc.NewLightningClient(conn).GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	return err
+}
+
+func TestGetClientConn(t *testing.T) {
+	fake := startNode(t, lndtest.Options{})
+	defer fake.Close()
+
+	tests := []struct {
+		name string
+		node Node
+		code codes.Code
+	}{{
+		name: "files",
+		node: Node{Host: "localhost", TLSCertPath: fake.CertPath, MacaroonPath: fake.MacaroonPath},
+	}, {
+		name: "inline certificate and hex macaroon",
+		node: Node{Host: "localhost", TLSCert: string(fake.CertPEM), MacaroonHex: hex.EncodeToString(fake.Macaroon)},
+	}, {
+		name: "base64 macaroon",
+		node: Node{Host: "localhost", TLSCertPath: fake.CertPath, MacaroonBase64: base64.StdEncoding.EncodeToString(fake.Macaroon)},
+	}, {
+		name: "pinned fingerprint",
+		node: Node{Host: "localhost", TLSSkipVerify: true, TLSCertFingerprint: fake.Fingerprint(), MacaroonPath: fake.MacaroonPath},
+	}, {
+		name: "wrong fingerprint",
+		node: Node{Host: "localhost", TLSSkipVerify: true, TLSCertFingerprint: strings.Repeat("00", 32), MacaroonPath: fake.MacaroonPath},
+		code: codes.Unavailable,
+	}, {
+		name: "unix socket",
+		node: Node{Host: "unix:///tmp/lnd.sock", TLSCertPath: fake.CertPath, MacaroonPath: fake.MacaroonPath},
+	}, {
+		name: "unix socket with inline certificate",
+		node: Node{Host: "unix:///tmp/lnd.sock", TLSCert: string(fake.CertPEM), MacaroonPath: fake.MacaroonPath},
+	}, {
+		name: "host not in certificate",
+		node: Node{Host: "lnd.example.com", TLSCertPath: fake.CertPath, MacaroonPath: fake.MacaroonPath},
+		code: codes.Unavailable,
+	}, {
+		name: "no macaroon sent",
+		node: Node{Host: "localhost", TLSCertPath: fake.CertPath, NoMacaroons: true},
+		code: codes.Unauthenticated,
+	}}
+
+	for _, test := range tests {
+		err := getInfo(fake, test.node)
+		if status.Code(err) != test.code {
+			t.Errorf("%s: expected code %v, got %v", test.name, test.code, err)
+		}
+	}
+}
+
+func TestGetClientConnWithoutMacaroons(t *testing.T) {
+	fake := startNode(t, lndtest.Options{NoMacaroons: true})
+	defer fake.Close()
+
+	node := Node{Host: "localhost", TLSCertPath: fake.CertPath, NoMacaroons: true}
+	if err := getInfo(fake, node); err != nil {
+		t.Errorf("expected to connect without macaroons, got %v", err)
+	}
+}
+
+func TestGetClientConnValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		node Node
+	}{
+		{"macaroon without macaroons", Node{NoMacaroons: true, MacaroonPath: "readonly.macaroon"}},
+		{"no macaroon", Node{TLSCertPath: "tls.cert"}},
+		{"hex and base64 macaroons", Node{MacaroonHex: "00", MacaroonBase64: "AA=="}},
+		{"fingerprint without skip verify", Node{MacaroonHex: "00", TLSCertFingerprint: strings.Repeat("00", 32)}},
+		{"invalid fingerprint", Node{MacaroonHex: "00", TLSSkipVerify: true, TLSCertFingerprint: "00"}},
+		{"certificate with skip verify", Node{MacaroonHex: "00", TLSSkipVerify: true, TLSCert: "cert"}},
+		{"unix socket without path", Node{Host: "unix://", MacaroonHex: "00"}},
+		{"invalid hex macaroon", Node{TLSSkipVerify: true, MacaroonHex: "zz"}},
+		{"invalid certificate", Node{TLSCert: "cert", MacaroonPath: "readonly.macaroon"}},
+		{"invalid base64 macaroon", Node{TLSSkipVerify: true, MacaroonBase64: "!!"}},
+		{"undecodable macaroon", Node{TLSSkipVerify: true, MacaroonHex: "00"}},
+	}
+
+	for _, test := range tests {
+		if _, err := getClientConn(test.node); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+		// The settings are rejected before connecting in the
+		// background, so they are not retried.
+		if _, err := connectNode(test.node); err == nil {
+			t.Errorf("%s: expected connectNode to fail", test.name)
+		}
+	}
+}
+
+// scrape connects a collector to the fake lnd and returns its /metrics
+// output.
+func scrape(t *testing.T, fake *lndtest.Node) string {
+	conn, err := getClientConn(Node{Host: "localhost", TLSCertPath: fake.CertPath, MacaroonPath: fake.MacaroonPath},
+		grpc.WithDialer(fake.Dialer()))
+	if err != nil {
+		t.Fatalf("getClientConn failed: %v", err)
+	}
+	defer conn.Close()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newNodeCollector(client.NewLightningClient(lnrpc.NewLightningClient(conn))))
+	server := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("scraping failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading metrics failed: %v", err)
+	}
+	return string(body)
+}
+
+// metricValue returns the value of the series in the metrics output.
+func metricValue(t *testing.T, metrics string, series string) float64 {
+	for _, line := range strings.Split(metrics, "\n") {
+		if strings.HasPrefix(line, series+" ") {
+			value, err := strconv.ParseFloat(strings.TrimPrefix(line, series+" "), 64)
+			if err != nil {
+				t.Fatalf("invalid value of %s: %v", series, err)
+			}
+			return value
+		}
+	}
+
+	t.Fatalf("series %s not found in:\n%s", series, metrics)
+	return 0
+}
+
+func TestMetrics(t *testing.T) {
+	fake := startNode(t, lndtest.Options{})
+	defer fake.Close()
+
+	fake.Script("GetInfo", lndtest.Script{Response: &lnrpc.GetInfoResponse{
+		IdentityPubkey: "02aaaa",
+		Alias:          "alice",
+		BlockHeight:    600000,
+		SyncedToChain:  true,
+	}})
+	fake.Script("WalletBalance", lndtest.Script{
+		Response: &lnrpc.WalletBalanceResponse{ConfirmedBalance: 100000, UnconfirmedBalance: 50000},
+		Latency:  100 * time.Millisecond,
+	})
+	fake.Script("ListChannels", lndtest.Script{Err: status.Error(codes.Internal, "channel db failure")})
+
+	metrics := scrape(t, fake)
+
+	expected := map[string]float64{
+		`lnd_up`:                        1,
+		`lnd_node_state{state="ready"}`: 1,
+		`lnd_block_height`:              600000,
+		`lnd_synced_to_chain`:           1,
+		`lnd_wallet_balance_satoshis{status="confirmed"}`:       100000,
+		`lnd_scrape_error{rpc="wallet_balance"}`:                0,
+		`lnd_scrape_error{rpc="list_channels"}`:                 1,
+		`lnd_exporter_scrape_errors_total{rpc="list_channels"}`: 1,
+	}
+	for series, value := range expected {
+		if got := metricValue(t, metrics, series); got != value {
+			t.Errorf("expected %s to be %v, got %v", series, value, got)
+		}
+	}
+	if duration := metricValue(t, metrics, `lnd_exporter_scrape_duration_seconds{rpc="wallet_balance"}`); duration < 0.1 {
+		t.Errorf("expected the wallet_balance duration to include the scripted latency, got %v", duration)
+	}
+	if !strings.Contains(metrics, `lnd_node_info{alias="alice",chains="",identity_pubkey="02aaaa"`) {
+		t.Errorf("node_info not found in:\n%s", metrics)
+	}
+}
+
+func TestMetricsWalletLocked(t *testing.T) {
+	fake := startNode(t, lndtest.Options{})
+	defer fake.Close()
+
+	fake.Script("GetInfo", lndtest.Script{Err: status.Error(codes.Unimplemented, "unknown service lnrpc.Lightning")})
+
+	metrics := scrape(t, fake)
+
+	if up := metricValue(t, metrics, "lnd_up"); up != 0 {
+		t.Errorf("expected lnd_up to be 0, got %v", up)
+	}
+	if locked := metricValue(t, metrics, `lnd_node_state{state="wallet_locked"}`); locked != 1 {
+		t.Errorf("expected the wallet_locked state, got %v", locked)
+	}
+	if calls := fake.Calls("WalletBalance"); calls != 0 {
+		t.Errorf("expected the collectors to be skipped while locked, got %d WalletBalance calls", calls)
+	}
+}
+
+func TestUnavailableCollector(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newUnavailableCollector())
+	server := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("scraping failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading metrics failed: %v", err)
+	}
+
+	metrics := string(body)
+	if up := metricValue(t, metrics, "lnd_up"); up != 0 {
+		t.Errorf("expected lnd_up to be 0, got %v", up)
+	}
+	if disconnected := metricValue(t, metrics, `lnd_node_state{state="disconnected"}`); disconnected != 1 {
+		t.Errorf("expected the disconnected state, got %v", disconnected)
+	}
+}
+
+func TestMetricsEveryRPCFailing(t *testing.T) {
+	fake := startNode(t, lndtest.Options{})
+	defer fake.Close()
+
+	for _, method := range []string{"WalletBalance", "ChannelBalance", "PendingChannels", "ListChannels", "ClosedChannels",
+		"ForwardingHistory", "FeeReport", "ListPeers", "GetNetworkInfo", "ListInvoices", "ListPayments"} {
+		fake.Script(method, lndtest.Script{Err: status.Error(codes.Internal, "failure")})
+	}
+
+	metrics := scrape(t, fake)
+
+	if up := metricValue(t, metrics, "lnd_up"); up != 1 {
+		t.Errorf("expected lnd_up to be 1 while the node is ready, got %v", up)
+	}
+	if scrapeError := metricValue(t, metrics, `lnd_scrape_error{rpc="wallet_balance"}`); scrapeError != 1 {
+		t.Errorf("expected the wallet_balance scrape error, got %v", scrapeError)
+	}
+}
@@ -0,0 +1,232 @@
+package lndtest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/macaroons"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	macaroon "gopkg.in/macaroon.v2"
+)
+
+// Options sets how a Node is started.
+type Options struct {
+	// NoMacaroons accepts calls without a macaroon, as lnd does when
+	// running with --no-macaroons.
+	NoMacaroons bool
+}
+
+// Node serves a Server over an in-memory connection, using a self-signed tls
+// certificate and requiring a macaroon baked with its own root key, as lnd
+// does. The certificate and macaroon are also written to files.
+type Node struct {
+	*Server
+
+	// CertPEM and Macaroon hold the tls certificate and the serialized
+	// macaroon, which are written to CertPath and MacaroonPath.
+	CertPEM      []byte
+	Macaroon     []byte
+	CertPath     string
+	MacaroonPath string
+
+	certDER    []byte
+	rootKey    []byte
+	dir        string
+	listener   *bufconn.Listener
+	grpcServer *grpc.Server
+}
+
+// StartNode starts a Node serving a new Server.
+func StartNode(options Options) (*Node, error) {
+	dir, err := ioutil.TempDir("", "lndtest")
+	if err != nil {
+		return nil, err
+	}
+
+	node := &Node{
+		Server:       NewServer(),
+		CertPath:     filepath.Join(dir, "tls.cert"),
+		MacaroonPath: filepath.Join(dir, "readonly.macaroon"),
+		rootKey:      []byte("lndtest root key"),
+		dir:          dir,
+		listener:     bufconn.Listen(1024 * 1024),
+	}
+
+	certificate, err := node.generateCertificate()
+	if err != nil {
+		node.Close()
+		return nil, err
+	}
+	if err := node.bakeMacaroon(); err != nil {
+		node.Close()
+		return nil, err
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.Creds(credentials.NewServerTLSFromCert(certificate)),
+	}
+	if !options.NoMacaroons {
+		opts = append(opts,
+			grpc.UnaryInterceptor(node.unaryMacaroonInterceptor),
+			grpc.StreamInterceptor(node.streamMacaroonInterceptor),
+		)
+	}
+	node.grpcServer = grpc.NewServer(opts...)
+	lnrpc.RegisterLightningServer(node.grpcServer, node.Server)
+	go node.grpcServer.Serve(node.listener)
+
+	return node, nil
+}
+
+// Close stops the node and removes its files.
+func (n *Node) Close() {
+	if n.grpcServer != nil {
+		n.grpcServer.Stop()
+	}
+	n.listener.Close()
+	os.RemoveAll(n.dir)
+}
+
+// Dialer dials the node, for the grpc.WithDialer option, whatever the address.
+func (n *Node) Dialer() func(string, time.Duration) (net.Conn, error) {
+	return func(string, time.Duration) (net.Conn, error) {
+		return n.listener.Dial()
+	}
+}
+
+// Dial connects to the node with its tls certificate and macaroon.
+func (n *Node) Dial() (*grpc.ClientConn, error) {
+	certPool := x509.NewCertPool()
+	certPool.AppendCertsFromPEM(n.CertPEM)
+
+	mac := &macaroon.Macaroon{}
+	if err := mac.UnmarshalBinary(n.Macaroon); err != nil {
+		return nil, err
+	}
+
+	return grpc.Dial("localhost:10009",
+		grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(certPool, "")),
+		grpc.WithPerRPCCredentials(macaroons.NewMacaroonCredential(mac)),
+		grpc.WithDialer(n.Dialer()),
+	)
+}
+
+// Fingerprint returns the hex encoded SHA-256 fingerprint of the tls
+// certificate.
+func (n *Node) Fingerprint() string {
+	sum := sha256.Sum256(n.certDER)
+	return hex.EncodeToString(sum[:])
+}
+
+// generateCertificate creates the self-signed tls certificate of the node,
+// valid for localhost.
+func (n *Node) generateCertificate() (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"lndtest"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	n.certDER, err = x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	n.CertPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: n.certDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	certificate, err := tls.X509KeyPair(n.CertPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return &certificate, ioutil.WriteFile(n.CertPath, n.CertPEM, 0600)
+}
+
+// bakeMacaroon creates the macaroon accepted by the node.
+func (n *Node) bakeMacaroon() error {
+	mac, err := macaroon.New(n.rootKey, []byte("lndtest"), "lnd", macaroon.LatestVersion)
+	if err != nil {
+		return err
+	}
+
+	n.Macaroon, err = mac.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(n.MacaroonPath, n.Macaroon, 0600)
+}
+
+// checkMacaroon verifies the macaroon sent in the metadata of a call, as lnd
+// does.
+func (n *Node) checkMacaroon(ctx context.Context) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md["macaroon"]) != 1 {
+		return status.Error(codes.Unauthenticated, "expected 1 macaroon")
+	}
+
+	macBytes, err := hex.DecodeString(md["macaroon"][0])
+	if err != nil {
+		return status.Error(codes.Unauthenticated, fmt.Sprintf("invalid macaroon encoding: %v", err))
+	}
+	mac := &macaroon.Macaroon{}
+	if err := mac.UnmarshalBinary(macBytes); err != nil {
+		return status.Error(codes.Unauthenticated, fmt.Sprintf("invalid macaroon: %v", err))
+	}
+	if _, err := mac.VerifySignature(n.rootKey, nil); err != nil {
+		return status.Error(codes.Unauthenticated, fmt.Sprintf("invalid macaroon signature: %v", err))
+	}
+
+	return nil
+}
+
+func (n *Node) unaryMacaroonInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := n.checkMacaroon(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (n *Node) streamMacaroonInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := n.checkMacaroon(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
@@ -0,0 +1,187 @@
+// Package lndtest provides a fake lnd serving scripted responses over an
+// in-memory gRPC connection, to test the exporter end to end.
+package lndtest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// Script sets how the server answers a method. Response is merged into the
+// empty response of the method, Events are sent on the stream of subscription
+// methods and Err, if set, is returned instead. Every call waits for Latency
+// before answering.
+type Script struct {
+	Response proto.Message
+	Events   []proto.Message
+	Err      error
+	Latency  time.Duration
+}
+
+// Server implements lnrpc.LightningServer, answering the methods called by
+// the exporter as scripted. Methods that were not scripted answer an empty
+// response, and subscriptions stay open without events. The methods the
+// exporter does not call answer an Unimplemented status.
+type Server struct {
+	unimplementedServer
+
+	mutex   sync.Mutex
+	scripts map[string]Script
+	calls   map[string]int
+}
+
+// NewServer creates a Server without scripts.
+func NewServer() *Server {
+	return &Server{
+		scripts: map[string]Script{},
+		calls:   map[string]int{},
+	}
+}
+
+// Script sets how the method, named as in lnrpc.LightningServer, is answered
+// from now on.
+func (s *Server) Script(method string, script Script) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.scripts[method] = script
+}
+
+// Calls returns how many times the method was called.
+func (s *Server) Calls(method string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.calls[method]
+}
+
+// script records a call to the method and waits for its latency, returning
+// its script.
+func (s *Server) script(ctx context.Context, method string) (Script, error) {
+	s.mutex.Lock()
+	s.calls[method]++
+	script := s.scripts[method]
+	s.mutex.Unlock()
+
+	if script.Latency > 0 {
+		select {
+		case <-time.After(script.Latency):
+		case <-ctx.Done():
+			return script, ctx.Err()
+		}
+	}
+
+	return script, script.Err
+}
+
+// respond answers a unary call to the method by filling resp.
+func (s *Server) respond(ctx context.Context, method string, resp proto.Message) error {
+	script, err := s.script(ctx, method)
+	if err != nil {
+		return err
+	}
+	if script.Response != nil {
+		proto.Merge(resp, script.Response)
+	}
+
+	return nil
+}
+
+// stream answers a subscription to the method by sending its events with
+// send, then keeps the stream open until the client cancels it.
+func (s *Server) stream(ctx context.Context, method string, send func(proto.Message) error) error {
+	script, err := s.script(ctx, method)
+	if err != nil {
+		return err
+	}
+	for _, event := range script.Events {
+		if err := send(event); err != nil {
+			return err
+		}
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *Server) GetInfo(ctx context.Context, in *lnrpc.GetInfoRequest) (*lnrpc.GetInfoResponse, error) {
+	resp := &lnrpc.GetInfoResponse{}
+	return resp, s.respond(ctx, "GetInfo", resp)
+}
+
+func (s *Server) WalletBalance(ctx context.Context, in *lnrpc.WalletBalanceRequest) (*lnrpc.WalletBalanceResponse, error) {
+	resp := &lnrpc.WalletBalanceResponse{}
+	return resp, s.respond(ctx, "WalletBalance", resp)
+}
+
+func (s *Server) ChannelBalance(ctx context.Context, in *lnrpc.ChannelBalanceRequest) (*lnrpc.ChannelBalanceResponse, error) {
+	resp := &lnrpc.ChannelBalanceResponse{}
+	return resp, s.respond(ctx, "ChannelBalance", resp)
+}
+
+func (s *Server) PendingChannels(ctx context.Context, in *lnrpc.PendingChannelsRequest) (*lnrpc.PendingChannelsResponse, error) {
+	resp := &lnrpc.PendingChannelsResponse{}
+	return resp, s.respond(ctx, "PendingChannels", resp)
+}
+
+func (s *Server) ListChannels(ctx context.Context, in *lnrpc.ListChannelsRequest) (*lnrpc.ListChannelsResponse, error) {
+	resp := &lnrpc.ListChannelsResponse{}
+	return resp, s.respond(ctx, "ListChannels", resp)
+}
+
+func (s *Server) ClosedChannels(ctx context.Context, in *lnrpc.ClosedChannelsRequest) (*lnrpc.ClosedChannelsResponse, error) {
+	resp := &lnrpc.ClosedChannelsResponse{}
+	return resp, s.respond(ctx, "ClosedChannels", resp)
+}
+
+func (s *Server) ForwardingHistory(ctx context.Context, in *lnrpc.ForwardingHistoryRequest) (*lnrpc.ForwardingHistoryResponse, error) {
+	resp := &lnrpc.ForwardingHistoryResponse{}
+	return resp, s.respond(ctx, "ForwardingHistory", resp)
+}
+
+func (s *Server) FeeReport(ctx context.Context, in *lnrpc.FeeReportRequest) (*lnrpc.FeeReportResponse, error) {
+	resp := &lnrpc.FeeReportResponse{}
+	return resp, s.respond(ctx, "FeeReport", resp)
+}
+
+func (s *Server) ListPeers(ctx context.Context, in *lnrpc.ListPeersRequest) (*lnrpc.ListPeersResponse, error) {
+	resp := &lnrpc.ListPeersResponse{}
+	return resp, s.respond(ctx, "ListPeers", resp)
+}
+
+func (s *Server) GetNetworkInfo(ctx context.Context, in *lnrpc.NetworkInfoRequest) (*lnrpc.NetworkInfo, error) {
+	resp := &lnrpc.NetworkInfo{}
+	return resp, s.respond(ctx, "GetNetworkInfo", resp)
+}
+
+func (s *Server) ListInvoices(ctx context.Context, in *lnrpc.ListInvoiceRequest) (*lnrpc.ListInvoiceResponse, error) {
+	resp := &lnrpc.ListInvoiceResponse{}
+	return resp, s.respond(ctx, "ListInvoices", resp)
+}
+
+func (s *Server) ListPayments(ctx context.Context, in *lnrpc.ListPaymentsRequest) (*lnrpc.ListPaymentsResponse, error) {
+	resp := &lnrpc.ListPaymentsResponse{}
+	return resp, s.respond(ctx, "ListPayments", resp)
+}
+
+func (s *Server) SubscribeInvoices(in *lnrpc.InvoiceSubscription, stream lnrpc.Lightning_SubscribeInvoicesServer) error {
+	return s.stream(stream.Context(), "SubscribeInvoices", func(event proto.Message) error {
+		return stream.Send(event.(*lnrpc.Invoice))
+	})
+}
+
+func (s *Server) SubscribeTransactions(in *lnrpc.GetTransactionsRequest, stream lnrpc.Lightning_SubscribeTransactionsServer) error {
+	return s.stream(stream.Context(), "SubscribeTransactions", func(event proto.Message) error {
+		return stream.Send(event.(*lnrpc.Transaction))
+	})
+}
+
+func (s *Server) SubscribeChannelGraph(in *lnrpc.GraphTopologySubscription, stream lnrpc.Lightning_SubscribeChannelGraphServer) error {
+	return s.stream(stream.Context(), "SubscribeChannelGraph", func(event proto.Message) error {
+		return stream.Send(event.(*lnrpc.GraphTopologyUpdate))
+	})
+}
@@ -0,0 +1,127 @@
+package lndtest
+
+import (
+	"context"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// unimplementedServer answers the lnrpc.LightningServer methods that Server
+// does not fake with an Unimplemented status, as lnd does for the methods it
+// does not serve.
+type unimplementedServer struct{}
+
+// unimplemented returns the error of a method that is not faked.
+func unimplemented(method string) error {
+	return status.Errorf(codes.Unimplemented, "method %s not implemented by the fake lnd", method)
+}
+
+func (unimplementedServer) GetTransactions(ctx context.Context, in *lnrpc.GetTransactionsRequest) (*lnrpc.TransactionDetails, error) {
+	return nil, unimplemented("GetTransactions")
+}
+
+func (unimplementedServer) SendCoins(ctx context.Context, in *lnrpc.SendCoinsRequest) (*lnrpc.SendCoinsResponse, error) {
+	return nil, unimplemented("SendCoins")
+}
+
+func (unimplementedServer) SendMany(ctx context.Context, in *lnrpc.SendManyRequest) (*lnrpc.SendManyResponse, error) {
+	return nil, unimplemented("SendMany")
+}
+
+func (unimplementedServer) NewAddress(ctx context.Context, in *lnrpc.NewAddressRequest) (*lnrpc.NewAddressResponse, error) {
+	return nil, unimplemented("NewAddress")
+}
+
+func (unimplementedServer) SignMessage(ctx context.Context, in *lnrpc.SignMessageRequest) (*lnrpc.SignMessageResponse, error) {
+	return nil, unimplemented("SignMessage")
+}
+
+func (unimplementedServer) VerifyMessage(ctx context.Context, in *lnrpc.VerifyMessageRequest) (*lnrpc.VerifyMessageResponse, error) {
+	return nil, unimplemented("VerifyMessage")
+}
+
+func (unimplementedServer) ConnectPeer(ctx context.Context, in *lnrpc.ConnectPeerRequest) (*lnrpc.ConnectPeerResponse, error) {
+	return nil, unimplemented("ConnectPeer")
+}
+
+func (unimplementedServer) DisconnectPeer(ctx context.Context, in *lnrpc.DisconnectPeerRequest) (*lnrpc.DisconnectPeerResponse, error) {
+	return nil, unimplemented("DisconnectPeer")
+}
+
+func (unimplementedServer) OpenChannelSync(ctx context.Context, in *lnrpc.OpenChannelRequest) (*lnrpc.ChannelPoint, error) {
+	return nil, unimplemented("OpenChannelSync")
+}
+
+func (unimplementedServer) OpenChannel(in *lnrpc.OpenChannelRequest, stream lnrpc.Lightning_OpenChannelServer) error {
+	return unimplemented("OpenChannel")
+}
+
+func (unimplementedServer) CloseChannel(in *lnrpc.CloseChannelRequest, stream lnrpc.Lightning_CloseChannelServer) error {
+	return unimplemented("CloseChannel")
+}
+
+func (unimplementedServer) AbandonChannel(ctx context.Context, in *lnrpc.AbandonChannelRequest) (*lnrpc.AbandonChannelResponse, error) {
+	return nil, unimplemented("AbandonChannel")
+}
+
+func (unimplementedServer) SendPayment(stream lnrpc.Lightning_SendPaymentServer) error {
+	return unimplemented("SendPayment")
+}
+
+func (unimplementedServer) SendPaymentSync(ctx context.Context, in *lnrpc.SendRequest) (*lnrpc.SendResponse, error) {
+	return nil, unimplemented("SendPaymentSync")
+}
+
+func (unimplementedServer) SendToRoute(stream lnrpc.Lightning_SendToRouteServer) error {
+	return unimplemented("SendToRoute")
+}
+
+func (unimplementedServer) SendToRouteSync(ctx context.Context, in *lnrpc.SendToRouteRequest) (*lnrpc.SendResponse, error) {
+	return nil, unimplemented("SendToRouteSync")
+}
+
+func (unimplementedServer) AddInvoice(ctx context.Context, in *lnrpc.Invoice) (*lnrpc.AddInvoiceResponse, error) {
+	return nil, unimplemented("AddInvoice")
+}
+
+func (unimplementedServer) LookupInvoice(ctx context.Context, in *lnrpc.PaymentHash) (*lnrpc.Invoice, error) {
+	return nil, unimplemented("LookupInvoice")
+}
+
+func (unimplementedServer) DecodePayReq(ctx context.Context, in *lnrpc.PayReqString) (*lnrpc.PayReq, error) {
+	return nil, unimplemented("DecodePayReq")
+}
+
+func (unimplementedServer) DeleteAllPayments(ctx context.Context, in *lnrpc.DeleteAllPaymentsRequest) (*lnrpc.DeleteAllPaymentsResponse, error) {
+	return nil, unimplemented("DeleteAllPayments")
+}
+
+func (unimplementedServer) DescribeGraph(ctx context.Context, in *lnrpc.ChannelGraphRequest) (*lnrpc.ChannelGraph, error) {
+	return nil, unimplemented("DescribeGraph")
+}
+
+func (unimplementedServer) GetChanInfo(ctx context.Context, in *lnrpc.ChanInfoRequest) (*lnrpc.ChannelEdge, error) {
+	return nil, unimplemented("GetChanInfo")
+}
+
+func (unimplementedServer) GetNodeInfo(ctx context.Context, in *lnrpc.NodeInfoRequest) (*lnrpc.NodeInfo, error) {
+	return nil, unimplemented("GetNodeInfo")
+}
+
+func (unimplementedServer) QueryRoutes(ctx context.Context, in *lnrpc.QueryRoutesRequest) (*lnrpc.QueryRoutesResponse, error) {
+	return nil, unimplemented("QueryRoutes")
+}
+
+func (unimplementedServer) StopDaemon(ctx context.Context, in *lnrpc.StopRequest) (*lnrpc.StopResponse, error) {
+	return nil, unimplemented("StopDaemon")
+}
+
+func (unimplementedServer) DebugLevel(ctx context.Context, in *lnrpc.DebugLevelRequest) (*lnrpc.DebugLevelResponse, error) {
+	return nil, unimplemented("DebugLevel")
+}
+
+func (unimplementedServer) UpdateChannelPolicy(ctx context.Context, in *lnrpc.PolicyUpdateRequest) (*lnrpc.PolicyUpdateResponse, error) {
+	return nil, unimplemented("UpdateChannelPolicy")
+}
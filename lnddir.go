@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// lndNetworks are the bitcoin networks lnd.conf can enable, in the order they
+// are checked.
+var lndNetworks = []string{"mainnet", "testnet", "regtest", "simnet"}
+
+// explicitSettings returns the names of the flags given on the command line,
+// in the configuration file or through their environment variable.
+func explicitSettings(envKeys map[string]string) map[string]bool {
+	explicit := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	for name, key := range envKeys {
+		if _, ok := os.LookupEnv(key); ok {
+			explicit[name] = true
+		}
+	}
+
+	return explicit
+}
+
+// applyLndDir fills in the settings of node that were not given explicitly
+// from the lnd directory: the tls certificate, the read only macaroon of the
+// network and the first rpclisten address, all of which can be overridden in
+// the lnd.conf of the directory.
+func applyLndDir(node *Node, dir string, network string, explicit map[string]bool) error {
+	conf, err := readLndConf(filepath.Join(dir, "lnd.conf"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if !explicit["lnd.network"] {
+		for _, n := range lndNetworks {
+			if conf["bitcoin."+n] == "1" || conf["bitcoin."+n] == "true" {
+				network = n
+			}
+		}
+	}
+
+	if !explicit["lnd.tls-cert-path"] {
+		node.TLSCertPath = filepath.Join(dir, "tls.cert")
+		if path, ok := conf["tlscertpath"]; ok {
+			node.TLSCertPath = expandPath(path)
+		}
+	}
+
+	if !explicit["lnd.macaroon-path"] && !node.NoMacaroons {
+		dataDir := filepath.Join(dir, "data")
+		if path, ok := conf["datadir"]; ok {
+			dataDir = expandPath(path)
+		}
+		node.MacaroonPath = filepath.Join(dataDir, "chain", "bitcoin", network, "readonly.macaroon")
+		if path, ok := conf["readonlymacaroonpath"]; ok {
+			node.MacaroonPath = expandPath(path)
+		}
+	}
+
+	if rpcListen, ok := conf["rpclisten"]; ok && !explicit["rpc.host"] {
+		if strings.Contains(rpcListen, "://") {
+			node.Host = rpcListen
+		} else if host, port, err := net.SplitHostPort(rpcListen); err == nil {
+			node.Host = host
+			if !explicit["rpc.port"] {
+				node.Port = port
+			}
+		} else {
+			node.Host = rpcListen
+		}
+
+		if ip := net.ParseIP(node.Host); node.Host == "" || (ip != nil && ip.IsUnspecified()) {
+			node.Host = "localhost"
+		}
+	}
+
+	return nil
+}
+
+// readLndConf reads the options of an lnd.conf file. Only the first value of
+// an option given more than once is kept.
+func readLndConf(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return map[string]string{}, err
+	}
+	defer file.Close()
+
+	conf := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		if _, ok := conf[key]; !ok {
+			conf[key] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	return conf, scanner.Err()
+}
+
+func expandPath(path string) string {
+	if strings.HasPrefix(path, "~") {
+		path = filepath.Join(os.Getenv("HOME"), path[1:])
+	}
+
+	return filepath.Clean(path)
+}
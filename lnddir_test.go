@@ -0,0 +1,148 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyLndDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lnddir")
+	if err != nil {
+		t.Fatalf("creating lnd directory failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The relative paths of the expected nodes are relative to the lnd
+	// directory.
+	flags := Node{Host: "localhost", Port: "10009", TLSCertPath: "/etc/exporter/tls.cert", MacaroonPath: "/etc/exporter/readonly.macaroon"}
+	defaultPaths := Node{Host: "localhost", Port: "10009", TLSCertPath: "tls.cert", MacaroonPath: "data/chain/bitcoin/mainnet/readonly.macaroon"}
+
+	tests := []struct {
+		name     string
+		conf     string
+		network  string
+		explicit []string
+		node     Node
+		expected Node
+	}{{
+		name:     "no lnd.conf",
+		node:     flags,
+		expected: defaultPaths,
+	}, {
+		name:     "network from lnd.conf",
+		conf:     "[Bitcoin]\nbitcoin.active=1\nbitcoin.testnet=1\n",
+		node:     flags,
+		expected: Node{Host: "localhost", Port: "10009", TLSCertPath: "tls.cert", MacaroonPath: "data/chain/bitcoin/testnet/readonly.macaroon"},
+	}, {
+		name:     "explicit network",
+		conf:     "bitcoin.testnet=true\n",
+		network:  "regtest",
+		explicit: []string{"lnd.network"},
+		node:     flags,
+		expected: Node{Host: "localhost", Port: "10009", TLSCertPath: "tls.cert", MacaroonPath: "data/chain/bitcoin/regtest/readonly.macaroon"},
+	}, {
+		name:     "datadir",
+		conf:     "datadir=/srv/lnd/data\n",
+		node:     flags,
+		expected: Node{Host: "localhost", Port: "10009", TLSCertPath: "tls.cert", MacaroonPath: "/srv/lnd/data/chain/bitcoin/mainnet/readonly.macaroon"},
+	}, {
+		name:     "tlscertpath and readonlymacaroonpath",
+		conf:     "datadir=/srv/lnd/data\ntlscertpath=/srv/lnd/tls.cert\nreadonlymacaroonpath=/srv/lnd/readonly.macaroon\n",
+		node:     flags,
+		expected: Node{Host: "localhost", Port: "10009", TLSCertPath: "/srv/lnd/tls.cert", MacaroonPath: "/srv/lnd/readonly.macaroon"},
+	}, {
+		name:     "explicit paths",
+		conf:     "tlscertpath=/srv/lnd/tls.cert\nreadonlymacaroonpath=/srv/lnd/readonly.macaroon\n",
+		explicit: []string{"lnd.tls-cert-path", "lnd.macaroon-path"},
+		node:     flags,
+		expected: flags,
+	}, {
+		name:     "no macaroons",
+		node:     Node{Host: "localhost", Port: "10009", NoMacaroons: true},
+		expected: Node{Host: "localhost", Port: "10009", TLSCertPath: "tls.cert", NoMacaroons: true},
+	}, {
+		name:     "rpclisten host and port",
+		conf:     "rpclisten=10.0.0.5:10010\n",
+		node:     flags,
+		expected: Node{Host: "10.0.0.5", Port: "10010", TLSCertPath: "tls.cert", MacaroonPath: "data/chain/bitcoin/mainnet/readonly.macaroon"},
+	}, {
+		name:     "rpclisten without port",
+		conf:     "rpclisten=10.0.0.5\n",
+		node:     flags,
+		expected: Node{Host: "10.0.0.5", Port: "10009", TLSCertPath: "tls.cert", MacaroonPath: "data/chain/bitcoin/mainnet/readonly.macaroon"},
+	}, {
+		name:     "rpclisten unspecified ipv4",
+		conf:     "rpclisten=0.0.0.0:10010\n",
+		node:     flags,
+		expected: Node{Host: "localhost", Port: "10010", TLSCertPath: "tls.cert", MacaroonPath: "data/chain/bitcoin/mainnet/readonly.macaroon"},
+	}, {
+		name:     "rpclisten unspecified ipv6",
+		conf:     "rpclisten=[::]:10010\n",
+		node:     flags,
+		expected: Node{Host: "localhost", Port: "10010", TLSCertPath: "tls.cert", MacaroonPath: "data/chain/bitcoin/mainnet/readonly.macaroon"},
+	}, {
+		name:     "rpclisten port only",
+		conf:     "rpclisten=:10010\n",
+		node:     flags,
+		expected: Node{Host: "localhost", Port: "10010", TLSCertPath: "tls.cert", MacaroonPath: "data/chain/bitcoin/mainnet/readonly.macaroon"},
+	}, {
+		name:     "rpclisten unix socket",
+		conf:     "rpclisten=unix:///var/run/lnd.sock\n",
+		node:     flags,
+		expected: Node{Host: "unix:///var/run/lnd.sock", Port: "10009", TLSCertPath: "tls.cert", MacaroonPath: "data/chain/bitcoin/mainnet/readonly.macaroon"},
+	}, {
+		name:     "first rpclisten",
+		conf:     "; rpclisten=10.0.0.4:10010\n# rpclisten=10.0.0.4:10010\nrpclisten = 10.0.0.5:10010\nrpclisten=10.0.0.6:10011\n",
+		node:     flags,
+		expected: Node{Host: "10.0.0.5", Port: "10010", TLSCertPath: "tls.cert", MacaroonPath: "data/chain/bitcoin/mainnet/readonly.macaroon"},
+	}, {
+		name:     "explicit host",
+		conf:     "rpclisten=10.0.0.5:10010\n",
+		explicit: []string{"rpc.host"},
+		node:     flags,
+		expected: defaultPaths,
+	}, {
+		name:     "explicit port",
+		conf:     "rpclisten=10.0.0.5:10010\n",
+		explicit: []string{"rpc.port"},
+		node:     flags,
+		expected: Node{Host: "10.0.0.5", Port: "10009", TLSCertPath: "tls.cert", MacaroonPath: "data/chain/bitcoin/mainnet/readonly.macaroon"},
+	}}
+
+	confPath := filepath.Join(dir, "lnd.conf")
+	for _, test := range tests {
+		os.Remove(confPath)
+		if test.conf != "" {
+			if err := ioutil.WriteFile(confPath, []byte(test.conf), 0600); err != nil {
+				t.Fatalf("writing lnd.conf failed: %v", err)
+			}
+		}
+
+		network := test.network
+		if network == "" {
+			network = "mainnet"
+		}
+		explicit := map[string]bool{}
+		for _, name := range test.explicit {
+			explicit[name] = true
+		}
+
+		expected := test.expected
+		for _, path := range []*string{&expected.TLSCertPath, &expected.MacaroonPath} {
+			if *path != "" && !filepath.IsAbs(*path) {
+				*path = filepath.Join(dir, *path)
+			}
+		}
+
+		node := test.node
+		if err := applyLndDir(&node, dir, network, explicit); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if node != expected {
+			t.Errorf("%s: expected %+v, got %+v", test.name, expected, node)
+		}
+	}
+}
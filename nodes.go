@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Node holds the settings to connect to a lightning node.
+type Node struct {
+	Name         string `yaml:"name"`
+	Host         string `yaml:"host"`
+	Port         string `yaml:"port"`
+	TLSCertPath  string `yaml:"tls_cert_path"`
+	MacaroonPath string `yaml:"macaroon_path"`
+
+	// MacaroonHex, MacaroonBase64 and TLSCert hold the credentials inline,
+	// taking precedence over the paths.
+	MacaroonHex    string `yaml:"macaroon_hex"`
+	MacaroonBase64 string `yaml:"macaroon_base64"`
+	TLSCert        string `yaml:"tls_cert"`
+
+	// NoMacaroons connects without a macaroon, to nodes running with
+	// --no-macaroons. TLSSkipVerify skips the verification of the tls
+	// certificate, pinning it to TLSCertFingerprint when given.
+	NoMacaroons        bool   `yaml:"no_macaroons"`
+	TLSSkipVerify      bool   `yaml:"tls_skip_verify"`
+	TLSCertFingerprint string `yaml:"tls_cert_fingerprint"`
+}
+
+type nodesFileContent struct {
+	Nodes []Node `yaml:"nodes"`
+}
+
+// loadNodes reads the nodes listed in the YAML file at path.
+func loadNodes(path string) ([]Node, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodesFile nodesFileContent
+	if err := yaml.UnmarshalStrict(content, &nodesFile); err != nil {
+		return nil, err
+	}
+	if len(nodesFile.Nodes) == 0 {
+		return nil, fmt.Errorf("no nodes listed in %s", path)
+	}
+	if err := validateNodes(nodesFile.Nodes); err != nil {
+		return nil, err
+	}
+
+	return nodesFile.Nodes, nil
+}
+
+// validateNodes checks that every node has a unique name, which is used as its
+// node label and probe target, and fills in the default host and port.
+func validateNodes(nodes []Node) error {
+	names := map[string]bool{}
+	for i, node := range nodes {
+		if node.Name == "" {
+			return fmt.Errorf("node %d has no name", i)
+		}
+		if names[node.Name] {
+			return fmt.Errorf("node name %s is repeated", node.Name)
+		}
+		names[node.Name] = true
+
+		if node.Host == "" {
+			nodes[i].Host = defaultRPCHost
+		}
+		if node.Port == "" {
+			nodes[i].Port = defaultRPCPort
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/platanus/lightning-prometheus-exporter/client"
+	"google.golang.org/grpc"
+)
+
+// nodeConnection holds the gRPC connection to a node, so it can be rebuilt
+// when the tls certificate or macaroon of the node change without replacing
+// the client used by its collectors.
+type nodeConnection struct {
+	node    Node
+	conn    *grpc.ClientConn
+	client  *client.LightningClient
+	modTime time.Time
+	mutex   sync.Mutex
+}
+
+const (
+	connectMinBackoff = time.Second
+	connectMaxBackoff = time.Minute
+)
+
+// connectNode creates the client of the node and connects it in the
+// background, so the exporter serves its metrics while the node cannot be
+// reached yet. Only invalid connection settings are returned as an error.
+func connectNode(node Node) (*nodeConnection, error) {
+	if err := validateConnectionMode(node); err != nil {
+		return nil, err
+	}
+
+	connection := &nodeConnection{
+		node:   node,
+		client: client.NewLightningClient(nil),
+	}
+	connection.client.SetTimeout(*rpcTimeout)
+	go connection.connect()
+
+	return connection, nil
+}
+
+// connect builds the connection to the node, retrying with an exponential
+// backoff while its tls certificate or macaroon cannot be loaded, e.g. before
+// lnd created them. Once built, gRPC itself reconnects whenever the node
+// restarts.
+func (c *nodeConnection) connect() {
+	backoff := connectMinBackoff
+	for {
+		err := c.tryConnect()
+		if err == nil {
+			return
+		}
+		log.Printf("Could not connect to node %s, retrying in %v: %v", c.node.Name, backoff, err)
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > connectMaxBackoff {
+			backoff = connectMaxBackoff
+		}
+	}
+}
+
+// tryConnect builds the connection to the node unless it was already built,
+// for instance by a reload.
+func (c *nodeConnection) tryConnect() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn != nil {
+		return nil
+	}
+
+	modTime := credentialsModTime(c.node)
+	conn, err := getClientConn(c.node)
+	if err != nil {
+		return err
+	}
+
+	c.client.SetRPCClient(lnrpc.NewLightningClient(conn))
+	c.conn = conn
+	c.modTime = modTime
+
+	return nil
+}
+
+// reload reads the tls certificate and macaroon again and replaces the
+// connection of the client. The current connection is kept if the new one
+// cannot be built.
+func (c *nodeConnection) reload() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	modTime := credentialsModTime(c.node)
+	conn, err := getClientConn(c.node)
+	if err != nil {
+		log.Printf("Could not reload connection to node %s: %v", c.node.Name, err)
+		return
+	}
+
+	c.client.SetRPCClient(lnrpc.NewLightningClient(conn))
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn = conn
+	c.modTime = modTime
+	log.Printf("Reloaded connection to node %s", c.node.Name)
+}
+
+// modified returns true when the tls certificate or macaroon files were
+// modified since the connection was built.
+func (c *nodeConnection) modified() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return credentialsModTime(c.node).After(c.modTime)
+}
+
+// reloadOnSignal reloads every connection when the exporter receives SIGHUP.
+func reloadOnSignal(connections []*nodeConnection) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	for range signals {
+		for _, connection := range connections {
+			connection.reload()
+		}
+	}
+}
+
+// watchCredentials reloads the connections whose tls certificate or macaroon
+// files were modified, checking them every interval.
+func watchCredentials(connections []*nodeConnection, interval time.Duration) {
+	for range time.Tick(interval) {
+		for _, connection := range connections {
+			if connection.modified() {
+				connection.reload()
+			}
+		}
+	}
+}
+
+// credentialsModTime returns the latest modification time of the tls
+// certificate and macaroon files of the node.
+func credentialsModTime(node Node) time.Time {
+	var modTime time.Time
+	for _, path := range []string{node.TLSCertPath, node.MacaroonPath} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err == nil && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+
+	return modTime
+}